@@ -0,0 +1,33 @@
+// Package actor describes who is making a request, independent of how they
+// authenticated. Services take an Actor instead of loose user ID and role strings.
+package actor
+
+type Actor struct {
+	UID         string   `json:"uid"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+func (a Actor) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Actor) HasPermission(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAnonymous reports whether the request carried no verified identity.
+func (a Actor) IsAnonymous() bool {
+	return a.UID == ""
+}
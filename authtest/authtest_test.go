@@ -0,0 +1,57 @@
+package authtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/policy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthAgainstEmulator(t *testing.T) {
+	e := Require(t)
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/me", middleware.Auth(e.Client, policy.Default()), func(c *gin.Context) {
+		user, _ := c.Get("user")
+		c.JSON(http.StatusOK, user)
+	})
+
+	// Test case 1: a user created with the admin role
+	token, err := e.NewUser(ctx, "admin1@email.com", "admin")
+	if err != nil {
+		t.Fatalf("NewUser error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Auth error: expected 200, got %d", w.Code)
+	}
+	var user middleware.User
+	_ = json.Unmarshal(w.Body.Bytes(), &user)
+	if user.Email != "admin1@email.com" || user.Role != "admin" {
+		t.Errorf("Auth error: unexpected user %+v", user)
+	}
+
+	// Test case 2: a user with no role yet gets the default one
+	token, err = e.NewUser(ctx, "user1@email.com", "")
+	if err != nil {
+		t.Fatalf("NewUser error: %v", err)
+	}
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), &user)
+	if user.Role != "user" {
+		t.Errorf("Auth error: expected user role, got %s", user.Role)
+	}
+}
@@ -0,0 +1,214 @@
+// Package automod screens quote submissions before a human sees them. A
+// Moderator looks at one submission and allows it, flags it for a closer look
+// or rejects it outright; a Chain runs several and combines their verdicts.
+// The built-in rules and their settings come from automod.json, which can be
+// replaced at startup with AUTOMOD_CONFIG.
+package automod
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ReviewerId is recorded as the reviewer of quotes approved automatically.
+const ReviewerId = "automod"
+
+// Decision is what a moderator wants done with a submission. Later decisions
+// in the list are more severe.
+type Decision string
+
+const (
+	Allow  Decision = "allow"
+	Flag   Decision = "flag"
+	Reject Decision = "reject"
+)
+
+func (d Decision) severity() int {
+	switch d {
+	case Flag:
+		return 1
+	case Reject:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Submission is the text being screened and who sent it.
+type Submission struct {
+	UserId string
+	Text   string
+}
+
+// Result is a moderator's verdict. Risk runs from 0 for clean text to 1 for
+// certain abuse, and Reasons explain anything other than Allow. Scores holds
+// any named scores a moderator wants moderators to see, such as "spam".
+type Result struct {
+	Decision Decision           `json:"decision"`
+	Risk     float64            `json:"risk"`
+	Reasons  []string           `json:"reasons,omitempty"`
+	Scores   map[string]float64 `json:"scores,omitempty"`
+}
+
+// Allowed is the verdict for a submission nothing objected to.
+var Allowed = Result{Decision: Allow}
+
+type Moderator interface {
+	Moderate(ctx context.Context, s Submission) Result
+}
+
+// Learner is a Moderator that learns from human moderators' decisions.
+type Learner interface {
+	Learn(ctx context.Context, s Submission, rejected bool) error
+}
+
+// Chain runs moderators in order. The combined decision is the most severe
+// one, the risks combine as independent odds and every reason is kept. A
+// rejection stops the chain.
+type Chain []Moderator
+
+func (c Chain) Moderate(ctx context.Context, s Submission) Result {
+	combined := Result{Decision: Allow}
+	clean := 1.0
+	for _, m := range c {
+		r := m.Moderate(ctx, s)
+		if r.Decision.severity() > combined.Decision.severity() {
+			combined.Decision = r.Decision
+		}
+		clean *= 1 - r.Risk
+		combined.Reasons = append(combined.Reasons, r.Reasons...)
+		for name, score := range r.Scores {
+			if combined.Scores == nil {
+				combined.Scores = map[string]float64{}
+			}
+			combined.Scores[name] = score
+		}
+		if r.Decision == Reject {
+			break
+		}
+	}
+	combined.Risk = 1 - clean
+	return combined
+}
+
+// Pipeline is the configured chain together with the rules for skipping the
+// human review queue and for sending published quotes back to it.
+type Pipeline struct {
+	Chain Chain
+	// TrustedAfter is how many approved quotes, with none rejected, make an
+	// author trusted. Clean submissions from trusted authors are approved
+	// without review. Zero turns auto-approval off.
+	TrustedAfter int
+	// HideAfterReports is how many open reader reports take a published
+	// quote back into the moderation queue. Zero leaves it to moderators.
+	HideAfterReports int
+}
+
+func (p *Pipeline) Moderate(ctx context.Context, s Submission) Result {
+	return p.Chain.Moderate(ctx, s)
+}
+
+// Learn tells every Learner in the chain how a human moderator decided on s.
+func (p *Pipeline) Learn(ctx context.Context, s Submission, rejected bool) error {
+	var errs []error
+	for _, m := range p.Chain {
+		if l, ok := m.(Learner); ok {
+			if err := l.Learn(ctx, s, rejected); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Trusted reports whether an author with this record skips review.
+func (p *Pipeline) Trusted(approved, rejected int) bool {
+	return p.TrustedAfter > 0 && approved >= p.TrustedAfter && rejected == 0
+}
+
+// Config is the layout of automod.json.
+type Config struct {
+	Words struct {
+		Reject []string `json:"reject"`
+		Flag   []string `json:"flag"`
+	} `json:"words"`
+	MinLength        int      `json:"min_length"`
+	MaxLength        int      `json:"max_length"`
+	Links            Decision `json:"links"`
+	MaxCapsRatio     float64  `json:"max_caps_ratio"`
+	MaxRepeatedChars int      `json:"max_repeated_chars"`
+	TrustedAfter     int      `json:"trusted_after"`
+	HideAfterReports int      `json:"hide_after_reports"`
+}
+
+//go:embed automod.json
+var defaultConfig []byte
+
+// Default returns the pipeline in the embedded automod.json.
+func Default() *Pipeline {
+	p, err := parse(defaultConfig)
+	if err != nil {
+		panic(fmt.Sprintf("automod: embedded automod.json is invalid: %v", err))
+	}
+	return p
+}
+
+// Load reads a pipeline configuration from path, or returns Default when path
+// is empty.
+func Load(path string) (*Pipeline, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("automod.Load: %w", err)
+	}
+	p, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("automod.Load %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func parse(raw []byte) (*Pipeline, error) {
+	var c Config
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	if c.Links != "" && c.Links.severity() == 0 && c.Links != Allow {
+		return nil, fmt.Errorf("unknown links decision %q", c.Links)
+	}
+	if c.MaxLength > 0 && c.MinLength > c.MaxLength {
+		return nil, fmt.Errorf("min_length %d is above max_length %d", c.MinLength, c.MaxLength)
+	}
+	return &Pipeline{Chain: c.Chain(), TrustedAfter: c.TrustedAfter, HideAfterReports: c.HideAfterReports}, nil
+}
+
+// Chain builds the built-in rules described by c. Rules left at their zero
+// value are skipped.
+func (c Config) Chain() Chain {
+	var chain Chain
+	if c.MinLength > 0 || c.MaxLength > 0 {
+		chain = append(chain, Length{Min: c.MinLength, Max: c.MaxLength})
+	}
+	if len(c.Words.Reject) > 0 {
+		chain = append(chain, NewWordList(Reject, c.Words.Reject...))
+	}
+	if len(c.Words.Flag) > 0 {
+		chain = append(chain, NewWordList(Flag, c.Words.Flag...))
+	}
+	if c.Links != "" && c.Links != Allow {
+		chain = append(chain, Links{Decision: c.Links})
+	}
+	if c.MaxCapsRatio > 0 {
+		chain = append(chain, Caps{MaxRatio: c.MaxCapsRatio})
+	}
+	if c.MaxRepeatedChars > 0 {
+		chain = append(chain, RepeatedChars{Max: c.MaxRepeatedChars})
+	}
+	return chain
+}
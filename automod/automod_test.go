@@ -0,0 +1,103 @@
+package automod
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	cases := []struct {
+		name string
+		mod  Moderator
+		text string
+		want Decision
+	}{
+		{"length ok", Length{Min: 3, Max: 20}, "Less is more.", Allow},
+		{"too short", Length{Min: 3, Max: 20}, "  ok ", Reject},
+		{"too long", Length{Min: 3, Max: 20}, "This quote goes on and on.", Reject},
+		{"word matches phrase", NewWordList(Flag, "Free Money"), "Get FREE money, now!", Flag},
+		{"word matches whole words only", NewWordList(Reject, "ass"), "A classic passage.", Allow},
+		{"url", Links{Decision: Flag}, "See https://example.com/x for more", Flag},
+		{"bare domain", Links{Decision: Reject}, "visit spam.biz today", Reject},
+		{"no link", Links{Decision: Flag}, "The end. Of course.", Allow},
+		{"shouting", Caps{MaxRatio: 0.7}, "THIS IS THE BEST QUOTE EVER", Flag},
+		{"short acronym", Caps{MaxRatio: 0.7}, "NASA", Allow},
+		{"repeated characters", RepeatedChars{Max: 3}, "Sooooo good", Flag},
+		{"repeated spaces", RepeatedChars{Max: 3}, "So      good", Allow},
+	}
+	for _, c := range cases {
+		if got := c.mod.Moderate(context.Background(), Submission{Text: c.text}); got.Decision != c.want {
+			t.Errorf("Moderate error: %s: expected %s, got %+v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	chain := Chain{Links{Decision: Flag}, Caps{MaxRatio: 0.5}, Length{Max: 40}, RepeatedChars{Max: 2}}
+
+	// Test case 1: clean text is allowed with no risk
+	if got := chain.Moderate(ctx, Submission{Text: "Simple is better."}); got.Decision != Allow || got.Risk != 0 || got.Reasons != nil {
+		t.Errorf("Moderate error: expected a clean result, got %+v", got)
+	}
+
+	// Test case 2: flags add up, the risk combining as independent odds
+	got := chain.Moderate(ctx, Submission{Text: "READ MORE AT HTTPS://EXAMPLE.COM"})
+	want := 1 - (1-linkRisk)*(1-capsRisk)
+	if got.Decision != Flag || math.Abs(got.Risk-want) > 1e-9 || len(got.Reasons) != 2 {
+		t.Errorf("Moderate error: expected two flags with risk %.2f, got %+v", want, got)
+	}
+
+	// Test case 3: a rejection wins and stops the chain
+	got = chain.Moderate(ctx, Submission{Text: "Too long for this chain, and also sooo repetitive"})
+	if got.Decision != Reject || got.Risk != 1 || len(got.Reasons) != 1 {
+		t.Errorf("Moderate error: expected a lone rejection, got %+v", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "automod.json")
+
+	// Test case 1: a custom configuration
+	_ = os.WriteFile(path, []byte(`{"words": {"reject": ["spam"]}, "links": "reject", "trusted_after": 2}`), 0o600)
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(p.Chain) != 2 || p.TrustedAfter != 2 {
+		t.Errorf("Load error: unexpected pipeline %+v", p)
+	}
+	if got := p.Moderate(context.Background(), Submission{Text: "Spam, spam and eggs"}); got.Decision != Reject {
+		t.Errorf("Moderate error: expected a rejection, got %+v", got)
+	}
+
+	// Test case 2: invalid settings are refused
+	_ = os.WriteFile(path, []byte(`{"links": "ban"}`), 0o600)
+	if _, err := Load(path); err == nil {
+		t.Error("Load error: unknown decision accepted")
+	}
+	_ = os.WriteFile(path, []byte(`{"min_length": 10, "max_length": 5}`), 0o600)
+	if _, err := Load(path); err == nil {
+		t.Error("Load error: min_length above max_length accepted")
+	}
+
+	// Test case 3: no path falls back to the embedded configuration
+	p, err = Load("")
+	if err != nil || len(p.Chain) == 0 {
+		t.Errorf("Load error: expected the default pipeline, got %+v, %v", p, err)
+	}
+}
+
+func TestTrusted(t *testing.T) {
+	p := &Pipeline{TrustedAfter: 3}
+	if p.Trusted(2, 0) || !p.Trusted(3, 0) || p.Trusted(10, 1) {
+		t.Error("Trusted error: unexpected verdict")
+	}
+	if (&Pipeline{}).Trusted(100, 0) {
+		t.Error("Trusted error: auto-approval should be off when TrustedAfter is zero")
+	}
+}
@@ -0,0 +1,138 @@
+package automod
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Risks reported by the built-in rules when they object.
+const (
+	rejectRisk   = 1.0
+	flagWordRisk = 0.5
+	linkRisk     = 0.6
+	capsRisk     = 0.4
+	repeatRisk   = 0.3
+	// minCapsLetters keeps short shouts such as "NASA" or "I AM" from
+	// tripping Caps.
+	minCapsLetters = 12
+)
+
+// Length rejects text shorter than Min or longer than Max characters. A zero
+// bound is not checked.
+type Length struct {
+	Min, Max int
+}
+
+func (l Length) Moderate(ctx context.Context, s Submission) Result {
+	n := utf8.RuneCountInString(strings.TrimSpace(s.Text))
+	switch {
+	case l.Min > 0 && n < l.Min:
+		return Result{Decision: Reject, Risk: rejectRisk, Reasons: []string{fmt.Sprintf("shorter than %d characters", l.Min)}}
+	case l.Max > 0 && n > l.Max:
+		return Result{Decision: Reject, Risk: rejectRisk, Reasons: []string{fmt.Sprintf("longer than %d characters", l.Max)}}
+	}
+	return Allowed
+}
+
+// WordList objects to text containing any of its words or phrases. Matching
+// ignores case and punctuation and only matches whole words.
+type WordList struct {
+	decision Decision
+	phrases  []string
+}
+
+func NewWordList(decision Decision, words ...string) WordList {
+	w := WordList{decision: decision}
+	for _, word := range words {
+		if phrase := normalizeWords(word); phrase != "" {
+			w.phrases = append(w.phrases, phrase)
+		}
+	}
+	return w
+}
+
+func (w WordList) Moderate(ctx context.Context, s Submission) Result {
+	text := " " + normalizeWords(s.Text) + " "
+	for _, phrase := range w.phrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			risk := flagWordRisk
+			if w.decision == Reject {
+				risk = rejectRisk
+			}
+			return Result{Decision: w.decision, Risk: risk, Reasons: []string{fmt.Sprintf("contains %q", phrase)}}
+		}
+	}
+	return Allowed
+}
+
+// normalizeWords lowercases s and separates its words with single spaces.
+func normalizeWords(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|co|biz|info|xyz|ru|ly|me)\b`)
+
+// Links objects to URLs and bare domain names.
+type Links struct {
+	Decision Decision
+}
+
+func (l Links) Moderate(ctx context.Context, s Submission) Result {
+	if link := linkPattern.FindString(s.Text); link != "" {
+		risk := linkRisk
+		if l.Decision == Reject {
+			risk = rejectRisk
+		}
+		return Result{Decision: l.Decision, Risk: risk, Reasons: []string{fmt.Sprintf("contains a link (%s)", link)}}
+	}
+	return Allowed
+}
+
+// Caps flags text whose letters are more than MaxRatio uppercase.
+type Caps struct {
+	MaxRatio float64
+}
+
+func (c Caps) Moderate(ctx context.Context, s Submission) Result {
+	letters, upper := 0, 0
+	for _, r := range s.Text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= minCapsLetters && float64(upper)/float64(letters) > c.MaxRatio {
+		return Result{Decision: Flag, Risk: capsRisk, Reasons: []string{"mostly capital letters"}}
+	}
+	return Allowed
+}
+
+// RepeatedChars flags text with a character repeated more than Max times in
+// a row, such as "sooooo" or "!!!!!!".
+type RepeatedChars struct {
+	Max int
+}
+
+func (rc RepeatedChars) Moderate(ctx context.Context, s Submission) Result {
+	var prev rune
+	run := 0
+	for _, r := range s.Text {
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		if run > rc.Max {
+			return Result{Decision: Flag, Risk: repeatRisk, Reasons: []string{fmt.Sprintf("repeats %q more than %d times", r, rc.Max)}}
+		}
+	}
+	return Allowed
+}
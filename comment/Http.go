@@ -0,0 +1,140 @@
+package comment
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/page"
+	"github.com/gin-gonic/gin"
+)
+
+// GetThreadHandler lists a quote's comments, taking limit, cursor and sort.
+// Threads read oldest first unless sort=newest.
+func GetThreadHandler(c *gin.Context, service CommentService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	sort := c.Query("sort")
+	if sort == "" {
+		sort = page.Oldest
+	}
+	p, err := page.Parse(c.Query("limit"), c.Query("cursor"), sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	thread, next, err := service.GetThread(c.Request.Context(), a, c.Param("id"), p)
+	if err != nil {
+		log.Println("GetThreadHandler: Error getting comments", err)
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"thread": thread, "next_cursor": next})
+}
+
+func CreateCommentHandler(c *gin.Context, service CommentService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var request CommentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	comment, err := service.CreateComment(c.Request.Context(), a, c.Param("id"), request.Body, request.ParentId)
+	if err != nil {
+		log.Println("CreateCommentHandler: Error failed to create comment", err)
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Comment created successfully", "comment": comment})
+}
+
+func UpdateCommentHandler(c *gin.Context, service CommentService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var request CommentUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	if err := service.UpdateComment(c.Request.Context(), a, c.Param("id"), request.Body); err != nil {
+		log.Println("UpdateCommentHandler: Error failed to update comment", err)
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully"})
+}
+
+func DeleteCommentHandler(c *gin.Context, service CommentService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	if err := service.DeleteComment(c.Request.Context(), a, c.Param("id")); err != nil {
+		log.Println("DeleteCommentHandler: Error failed to delete comment", err)
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+func LockThreadHandler(c *gin.Context, service CommentService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var request LockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	if err := service.LockThread(c.Request.Context(), a, c.Param("id"), request.Locked); err != nil {
+		log.Println("LockThreadHandler: Error failed to lock comments", err)
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"locked": request.Locked})
+}
+
+func writeError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidRequestBody, ErrInvalidComment, ErrInvalidParent:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrQuoteNotFound, ErrCommentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrThreadLocked, ErrCommentDeleted:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func getActorFromCtx(ctx *gin.Context) (actor.Actor, bool) {
+	return middleware.CurrentActor(ctx)
+}
@@ -0,0 +1,19 @@
+package comment
+
+import "errors"
+
+var (
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrQuoteNotFound      = errors.New("quote not found")
+	ErrInvalidRequestBody = errors.New("invalid request body")
+	ErrInvalidComment     = errors.New("comment must be between 1 and 2000 characters")
+	ErrInvalidParent      = errors.New("replies can only be made to comments on the same quote that are not replies themselves")
+	ErrNotAuthorized      = errors.New("unauthorized access")
+	ErrThreadLocked       = errors.New("comments on this quote are locked")
+	ErrCommentDeleted     = errors.New("comment has been deleted")
+	ErrCreateComment      = errors.New("failed to create comment")
+	ErrUpdateComment      = errors.New("failed to update comment")
+	ErrDeletingComment    = errors.New("failed to delete comment")
+	ErrGettingComments    = errors.New("failed to get comments")
+	ErrLockingThread      = errors.New("failed to lock comments")
+)
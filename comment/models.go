@@ -0,0 +1,41 @@
+package comment
+
+import "time"
+
+// Comment is a remark on a quote, or a reply to one. Deleted comments keep
+// their place in the thread with an empty body.
+type Comment struct {
+	Id       string `json:"id"`
+	QuoteId  string `json:"quote_id"`
+	ParentId string `json:"parent_id,omitempty"`
+	UserId   string `json:"user_id"`
+	Body     string `json:"body"`
+	// EditedAt is set once the author changes the body.
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	Deleted   bool       `json:"deleted"`
+	CreatedAt time.Time  `json:"created_at"`
+	// Replies are only filled in on top-level comments, oldest first.
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+// Thread is one page of a quote's comments.
+type Thread struct {
+	QuoteId  string     `json:"quote_id"`
+	Locked   bool       `json:"locked"`
+	LockedAt *time.Time `json:"locked_at,omitempty"`
+	Comments []*Comment `json:"comments"`
+}
+
+type CommentRequest struct {
+	Body string `json:"body"`
+	// ParentId replies to a top-level comment.
+	ParentId string `json:"parent_id"`
+}
+
+type CommentUpdateRequest struct {
+	Body string `json:"body"`
+}
+
+type LockRequest struct {
+	Locked bool `json:"locked"`
+}
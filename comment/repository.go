@@ -0,0 +1,237 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cprime50/fire-go/page"
+	"github.com/google/uuid"
+)
+
+type CommentRepository interface {
+	GetQuote(ctx context.Context, quoteId string) (*QuoteInfo, error)
+	CreateComment(ctx context.Context, c *Comment) error
+	GetComment(ctx context.Context, commentId string) (*Comment, error)
+	UpdateComment(ctx context.Context, commentId, body string, editedAt time.Time) error
+	DeleteComment(ctx context.Context, commentId, deletedBy string, deletedAt time.Time) error
+	ListComments(ctx context.Context, quoteId string, p page.Params) ([]*Comment, error)
+	GetLock(ctx context.Context, quoteId string) (*time.Time, error)
+	SetLock(ctx context.Context, quoteId string, locked bool, by string, at time.Time) error
+}
+
+// QuoteInfo is what the comment package needs to know about a quote.
+type QuoteInfo struct {
+	Id     string
+	UserId string
+	Status string
+}
+
+// SQLiteCommentRepository stores comments in the comments table and thread
+// locks in comment_threads. It reads quotes directly to check they exist.
+type SQLiteCommentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) *SQLiteCommentRepository {
+	return &SQLiteCommentRepository{db: db}
+}
+
+func (r *SQLiteCommentRepository) GetQuote(ctx context.Context, quoteId string) (*QuoteInfo, error) {
+	q := &QuoteInfo{}
+	err := r.db.QueryRowContext(ctx, "SELECT id, user_id, status FROM quotes WHERE id = $1", quoteId).Scan(&q.Id, &q.UserId, &q.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("GetQuote: %w", err)
+	}
+	return q, nil
+}
+
+func (r *SQLiteCommentRepository) CreateComment(ctx context.Context, c *Comment) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("uuid.NewRandom: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx,
+		"INSERT INTO comments (id, quote_id, parent_id, user_id, body, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		id.String(),
+		c.QuoteId,
+		sql.NullString{String: c.ParentId, Valid: c.ParentId != ""},
+		c.UserId,
+		c.Body,
+		c.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("CreateComment error: %w", err)
+	}
+	c.Id = id.String()
+	return nil
+}
+
+const commentColumns = "id, quote_id, parent_id, user_id, body, created_at, edited_at, deleted_at"
+
+func (r *SQLiteCommentRepository) GetComment(ctx context.Context, commentId string) (*Comment, error) {
+	c, err := scanComment(r.db.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = $1", commentId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("GetComment: %w", err)
+	}
+	return c, nil
+}
+
+func (r *SQLiteCommentRepository) UpdateComment(ctx context.Context, commentId, body string, editedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE comments SET body = $1, edited_at = $2 WHERE id = $3 AND deleted_at IS NULL",
+		body, editedAt, commentId,
+	)
+	if err != nil {
+		return fmt.Errorf("UpdateComment error: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// DeleteComment soft deletes a comment. The body is kept for moderators but
+// never shown again.
+func (r *SQLiteCommentRepository) DeleteComment(ctx context.Context, commentId, deletedBy string, deletedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE comments SET deleted_at = $1, deleted_by = $2 WHERE id = $3 AND deleted_at IS NULL",
+		deletedAt, deletedBy, commentId,
+	)
+	if err != nil {
+		return fmt.Errorf("DeleteComment error: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// ListComments returns one page of a quote's top-level comments, fetched
+// with p.OrderBy, with all their replies attached oldest first.
+func (r *SQLiteCommentRepository) ListComments(ctx context.Context, quoteId string, p page.Params) ([]*Comment, error) {
+	after, afterArgs := p.Where(2)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE quote_id = $1 AND parent_id IS NULL AND "+after+" "+p.OrderBy(),
+		append([]any{quoteId}, afterArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListComments: %w", err)
+	}
+	comments, err := queryComments(rows)
+	if err != nil {
+		return nil, fmt.Errorf("ListComments: %w", err)
+	}
+	if len(comments) == 0 {
+		return comments, nil
+	}
+
+	byId := make(map[string]*Comment, len(comments))
+	ids := make([]string, len(comments))
+	for i, c := range comments {
+		byId[c.Id] = c
+		ids[i] = c.Id
+	}
+	in, args := inClause(1, ids)
+	rows, err = r.db.QueryContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE parent_id IN ("+in+") ORDER BY created_at, id",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ListComments replies: %w", err)
+	}
+	replies, err := queryComments(rows)
+	if err != nil {
+		return nil, fmt.Errorf("ListComments replies: %w", err)
+	}
+	for _, reply := range replies {
+		parent := byId[reply.ParentId]
+		parent.Replies = append(parent.Replies, reply)
+	}
+	return comments, nil
+}
+
+// GetLock returns when a quote's comments were locked, or nil.
+func (r *SQLiteCommentRepository) GetLock(ctx context.Context, quoteId string) (*time.Time, error) {
+	var lockedAt time.Time
+	err := r.db.QueryRowContext(ctx, "SELECT locked_at FROM comment_threads WHERE quote_id = $1", quoteId).Scan(&lockedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetLock: %w", err)
+	}
+	return &lockedAt, nil
+}
+
+func (r *SQLiteCommentRepository) SetLock(ctx context.Context, quoteId string, locked bool, by string, at time.Time) error {
+	var err error
+	if locked {
+		_, err = r.db.ExecContext(ctx,
+			"INSERT INTO comment_threads (quote_id, locked_at, locked_by) VALUES ($1, $2, $3) ON CONFLICT (quote_id) DO NOTHING",
+			quoteId, at, by,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx, "DELETE FROM comment_threads WHERE quote_id = $1", quoteId)
+	}
+	if err != nil {
+		return fmt.Errorf("SetLock error: %w", err)
+	}
+	return nil
+}
+
+func queryComments(rows *sql.Rows) ([]*Comment, error) {
+	defer rows.Close()
+	comments := []*Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+	return comments, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanComment(row scanner) (*Comment, error) {
+	c := &Comment{}
+	var parentId sql.NullString
+	var editedAt, deletedAt sql.NullTime
+	if err := row.Scan(&c.Id, &c.QuoteId, &parentId, &c.UserId, &c.Body, &c.CreatedAt, &editedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	c.ParentId = parentId.String
+	if editedAt.Valid {
+		c.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		c.Deleted = true
+		c.Body = ""
+	}
+	return c, nil
+}
+
+func inClause(n int, values []string) (string, []any) {
+	placeholders := make([]string, len(values))
+	args := make([]any, len(values))
+	for i, v := range values {
+		placeholders[i] = fmt.Sprintf("$%d", n+i)
+		args[i] = v
+	}
+	return strings.Join(placeholders, ", "), args
+}
@@ -0,0 +1,264 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/policy"
+	"github.com/cprime50/fire-go/quote"
+)
+
+// MaxCommentLength is the longest comment body, in characters.
+const MaxCommentLength = 2000
+
+type CommentService interface {
+	GetThread(ctx context.Context, a actor.Actor, quoteId string, p page.Params) (*Thread, string, error)
+	CreateComment(ctx context.Context, a actor.Actor, quoteId, body, parentId string) (*Comment, error)
+	UpdateComment(ctx context.Context, a actor.Actor, commentId, body string) error
+	DeleteComment(ctx context.Context, a actor.Actor, commentId string) error
+	LockThread(ctx context.Context, a actor.Actor, quoteId string, locked bool) error
+}
+
+type CommentServiceImpl struct {
+	repo CommentRepository
+}
+
+func NewCommentService(repo CommentRepository) *CommentServiceImpl {
+	return &CommentServiceImpl{repo: repo}
+}
+
+// GetThread returns one page of a quote's top-level comments with their
+// replies, and the cursor for the next page. Comments on unpublished quotes
+// are only visible to those who can read the quote.
+func (s *CommentServiceImpl) GetThread(ctx context.Context, a actor.Actor, quoteId string, p page.Params) (*Thread, string, error) {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return nil, "", ErrInvalidRequestBody
+	}
+	q, err := s.getQuote(ctx, quoteId, ErrGettingComments)
+	if err != nil {
+		return nil, "", err
+	}
+	if q.Status != string(quote.StatusApproved) && !policy.Can(a, policy.QuoteRead, policy.OwnedBy(q.UserId)) {
+		log.Println("Error: Not authorized")
+		return nil, "", ErrNotAuthorized
+	}
+
+	lockedAt, err := s.repo.GetLock(ctx, quoteId)
+	if err != nil {
+		log.Println("Error getting comments:", err)
+		return nil, "", ErrGettingComments
+	}
+	comments, err := s.repo.ListComments(ctx, quoteId, p)
+	if err != nil {
+		log.Println("Error getting comments:", err)
+		return nil, "", ErrGettingComments
+	}
+	comments, next := page.Trim(comments, p, func(c *Comment) (time.Time, string) { return c.CreatedAt, c.Id })
+	return &Thread{QuoteId: quoteId, Locked: lockedAt != nil, LockedAt: lockedAt, Comments: comments}, next, nil
+}
+
+// CreateComment adds a comment to a published quote, or a reply when
+// parentId names a top-level comment on the same quote.
+func (s *CommentServiceImpl) CreateComment(ctx context.Context, a actor.Actor, quoteId, body, parentId string) (*Comment, error) {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return nil, ErrInvalidRequestBody
+	}
+	body, err := normalizeBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Can(a, policy.CommentCreate, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, ErrNotAuthorized
+	}
+
+	q, err := s.getQuote(ctx, quoteId, ErrCreateComment)
+	if err != nil {
+		return nil, err
+	}
+	if q.Status != string(quote.StatusApproved) {
+		log.Printf("Error: quote %s is %s and cannot be commented on", quoteId, q.Status)
+		return nil, ErrQuoteNotFound
+	}
+	if err := s.checkUnlocked(ctx, quoteId, ErrCreateComment); err != nil {
+		return nil, err
+	}
+
+	if parentId != "" {
+		parent, err := s.repo.GetComment(ctx, parentId)
+		if err != nil {
+			if errors.Is(err, ErrCommentNotFound) {
+				log.Println("Error: Parent comment not found")
+				return nil, ErrInvalidParent
+			}
+			log.Println("Error creating comment:", err)
+			return nil, ErrCreateComment
+		}
+		if parent.QuoteId != quoteId || parent.ParentId != "" {
+			log.Printf("Error: comment %s cannot be replied to on quote %s", parentId, quoteId)
+			return nil, ErrInvalidParent
+		}
+		if parent.Deleted {
+			log.Println("Error: Parent comment deleted")
+			return nil, ErrCommentDeleted
+		}
+	}
+
+	c := &Comment{
+		QuoteId:   quoteId,
+		ParentId:  parentId,
+		UserId:    a.UID,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateComment(ctx, c); err != nil {
+		log.Println("Error creating comment:", err)
+		return nil, ErrCreateComment
+	}
+	return c, nil
+}
+
+// UpdateComment changes a comment's body. Like quotes, comments can be
+// edited by their author or by anyone allowed to edit every comment.
+func (s *CommentServiceImpl) UpdateComment(ctx context.Context, a actor.Actor, commentId, body string) error {
+	if a.UID == "" || commentId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+	body, err := normalizeBody(body)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.getComment(ctx, commentId, ErrUpdateComment)
+	if err != nil {
+		return err
+	}
+	if !policy.Can(a, policy.CommentUpdate, policy.OwnedBy(c.UserId)) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	if c.Deleted {
+		log.Println("Error: Comment deleted")
+		return ErrCommentDeleted
+	}
+	if err := s.checkUnlocked(ctx, c.QuoteId, ErrUpdateComment); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateComment(ctx, commentId, body, time.Now()); err != nil {
+		if errors.Is(err, ErrCommentNotFound) {
+			return ErrCommentDeleted
+		}
+		log.Println("Error updating comment:", err)
+		return ErrUpdateComment
+	}
+	return nil
+}
+
+// DeleteComment soft deletes a comment so its replies keep their place.
+// Comments can still be deleted once a thread is locked.
+func (s *CommentServiceImpl) DeleteComment(ctx context.Context, a actor.Actor, commentId string) error {
+	if a.UID == "" || commentId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+
+	c, err := s.getComment(ctx, commentId, ErrDeletingComment)
+	if err != nil {
+		return err
+	}
+	if !policy.Can(a, policy.CommentDelete, policy.OwnedBy(c.UserId)) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	if c.Deleted {
+		log.Println("Error: Comment already deleted")
+		return ErrCommentDeleted
+	}
+
+	if err := s.repo.DeleteComment(ctx, commentId, a.UID, time.Now()); err != nil {
+		if errors.Is(err, ErrCommentNotFound) {
+			return ErrCommentDeleted
+		}
+		log.Println("Error deleting comment:", err)
+		return ErrDeletingComment
+	}
+	return nil
+}
+
+// LockThread stops or allows new comments, replies and edits on a quote.
+func (s *CommentServiceImpl) LockThread(ctx context.Context, a actor.Actor, quoteId string, locked bool) error {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+	if !policy.Can(a, policy.CommentLock, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	if _, err := s.getQuote(ctx, quoteId, ErrLockingThread); err != nil {
+		return err
+	}
+	if err := s.repo.SetLock(ctx, quoteId, locked, a.UID, time.Now()); err != nil {
+		log.Println("Error locking comments:", err)
+		return ErrLockingThread
+	}
+	return nil
+}
+
+func (s *CommentServiceImpl) getQuote(ctx context.Context, quoteId string, failed error) (*QuoteInfo, error) {
+	q, err := s.repo.GetQuote(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return nil, ErrQuoteNotFound
+		}
+		log.Println("Error getting quote:", err)
+		return nil, failed
+	}
+	return q, nil
+}
+
+func (s *CommentServiceImpl) getComment(ctx context.Context, commentId string, failed error) (*Comment, error) {
+	c, err := s.repo.GetComment(ctx, commentId)
+	if err != nil {
+		if errors.Is(err, ErrCommentNotFound) {
+			log.Println("Error: Comment not found")
+			return nil, ErrCommentNotFound
+		}
+		log.Println("Error getting comment:", err)
+		return nil, failed
+	}
+	return c, nil
+}
+
+func (s *CommentServiceImpl) checkUnlocked(ctx context.Context, quoteId string, failed error) error {
+	lockedAt, err := s.repo.GetLock(ctx, quoteId)
+	if err != nil {
+		log.Println("Error getting thread lock:", err)
+		return failed
+	}
+	if lockedAt != nil {
+		log.Printf("Error: comments on quote %s are locked", quoteId)
+		return ErrThreadLocked
+	}
+	return nil
+}
+
+func normalizeBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > MaxCommentLength {
+		log.Println("Error: Invalid comment length")
+		return "", ErrInvalidComment
+	}
+	return body, nil
+}
@@ -0,0 +1,154 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/db"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/policy"
+)
+
+var (
+	testDb    *sql.DB
+	firstPage = page.Params{Limit: page.DefaultLimit, Sort: page.Oldest}
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	testDb, err = db.ConnectTest()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = db.Migrate(testDb); err != nil {
+		log.Fatal(err)
+	}
+	defer testDb.Close()
+
+	os.Exit(m.Run())
+}
+
+func testActor(uid, role string) actor.Actor {
+	return actor.Actor{UID: uid, Roles: []string{role}, Permissions: policy.Default().PermissionsFor(role)}
+}
+
+// insertQuote stores a quote with the given status directly.
+func insertQuote(t *testing.T, id, userId, status string) {
+	t.Helper()
+	if _, err := testDb.Exec("INSERT INTO quotes (id, user_id, quote, status) VALUES ($1, $2, $3, $4)", id, userId, "quote "+id, status); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommentService(t *testing.T) {
+	if _, err := testDb.Exec("DELETE FROM quotes; DELETE FROM comments; DELETE FROM comment_threads"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s := NewCommentService(NewCommentRepository(testDb))
+	user1 := testActor("user1", policy.RoleUser)
+	user2 := testActor("user2", policy.RoleUser)
+	admin := testActor("admin1", policy.RoleAdmin)
+	insertQuote(t, "published", "user1", "approved")
+	insertQuote(t, "pending", "user1", "pending")
+
+	// Test case 1: only published quotes can be commented on, with a valid body
+	if _, err := s.CreateComment(ctx, user2, "pending", "Nice", ""); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("CreateComment error: expected ErrQuoteNotFound, got %v", err)
+	}
+	for _, body := range []string{"   ", strings.Repeat("a", MaxCommentLength+1)} {
+		if _, err := s.CreateComment(ctx, user2, "published", body, ""); !errors.Is(err, ErrInvalidComment) {
+			t.Errorf("CreateComment error: expected ErrInvalidComment, got %v", err)
+		}
+	}
+
+	// Test case 2: replies nest one level deep
+	top, err := s.CreateComment(ctx, user2, "published", " So true ", "")
+	if err != nil || top.Body != "So true" {
+		t.Fatalf("CreateComment error: got %+v, %v", top, err)
+	}
+	reply, err := s.CreateComment(ctx, user1, "published", "Thanks", top.Id)
+	if err != nil {
+		t.Fatalf("CreateComment error: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, user2, "published", "Nested", reply.Id); !errors.Is(err, ErrInvalidParent) {
+		t.Errorf("CreateComment error: expected ErrInvalidParent, got %v", err)
+	}
+	thread, _, err := s.GetThread(ctx, user2, "published", firstPage)
+	if err != nil || len(thread.Comments) != 1 || len(thread.Comments[0].Replies) != 1 || thread.Comments[0].Replies[0].Id != reply.Id {
+		t.Fatalf("GetThread error: expected one comment with one reply, got %+v, %v", thread, err)
+	}
+
+	// Test case 3: only the author or an admin can edit or delete
+	if err := s.UpdateComment(ctx, user1, top.Id, "Hijacked"); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("UpdateComment error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.UpdateComment(ctx, user2, top.Id, "So very true"); err != nil {
+		t.Errorf("UpdateComment error: %v", err)
+	}
+	if err := s.DeleteComment(ctx, user2, reply.Id); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("DeleteComment error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.DeleteComment(ctx, admin, top.Id); err != nil {
+		t.Errorf("DeleteComment error: %v", err)
+	}
+
+	// Test case 4: deleted comments keep their place without a body
+	thread, _, err = s.GetThread(ctx, user2, "published", firstPage)
+	if err != nil || len(thread.Comments) != 1 {
+		t.Fatalf("GetThread error: got %+v, %v", thread, err)
+	}
+	if c := thread.Comments[0]; !c.Deleted || c.Body != "" || c.EditedAt == nil || len(c.Replies) != 1 {
+		t.Errorf("GetThread error: expected an edited, deleted comment with its reply, got %+v", c)
+	}
+	if err := s.UpdateComment(ctx, user2, top.Id, "Back"); !errors.Is(err, ErrCommentDeleted) {
+		t.Errorf("UpdateComment error: expected ErrCommentDeleted, got %v", err)
+	}
+	if _, err := s.CreateComment(ctx, user1, "published", "Reply", top.Id); !errors.Is(err, ErrCommentDeleted) {
+		t.Errorf("CreateComment error: expected ErrCommentDeleted, got %v", err)
+	}
+
+	// Test case 5: locked threads take no new comments or edits, but allow deletes
+	if err := s.LockThread(ctx, user1, "published", true); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("LockThread error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.LockThread(ctx, admin, "published", true); err != nil {
+		t.Fatalf("LockThread error: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, user2, "published", "Late", ""); !errors.Is(err, ErrThreadLocked) {
+		t.Errorf("CreateComment error: expected ErrThreadLocked, got %v", err)
+	}
+	if err := s.UpdateComment(ctx, user1, reply.Id, "Edited"); !errors.Is(err, ErrThreadLocked) {
+		t.Errorf("UpdateComment error: expected ErrThreadLocked, got %v", err)
+	}
+	if err := s.DeleteComment(ctx, user1, reply.Id); err != nil {
+		t.Errorf("DeleteComment error: %v", err)
+	}
+	if thread, _, err := s.GetThread(ctx, user2, "published", firstPage); err != nil || !thread.Locked {
+		t.Errorf("GetThread error: expected a locked thread, got %+v, %v", thread, err)
+	}
+	if err := s.LockThread(ctx, admin, "published", false); err != nil {
+		t.Fatalf("LockThread error: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, user2, "published", "Open again", ""); err != nil {
+		t.Errorf("CreateComment error: %v", err)
+	}
+
+	// Test case 6: deleting the quote deletes its comments and lock
+	if err := s.LockThread(ctx, admin, "published", true); err != nil {
+		t.Fatalf("LockThread error: %v", err)
+	}
+	if _, err := testDb.Exec("DELETE FROM quotes WHERE id = 'published'"); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := testDb.QueryRow("SELECT (SELECT COUNT(*) FROM comments) + (SELECT COUNT(*) FROM comment_threads)").Scan(&left); err != nil || left != 0 {
+		t.Errorf("DeleteQuote error: expected comments to be deleted, got %d rows, %v", left, err)
+	}
+}
@@ -2,28 +2,27 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 
+	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var Db *sql.DB
-
 func Connect() (*sql.DB, error) {
-	var err error
-	Db, err = sql.Open("sqlite3", "user.db?cache=shared&mode=rwc&_journal_mode=WAL&busy_timeout=10000")
-	Db.SetMaxOpenConns(1)
-	if err != nil {
-		return nil, err
-	}
-	return Db, nil
+	return open("user.db?cache=shared&mode=rwc&_journal_mode=WAL&busy_timeout=10000")
 }
 
+// ConnectTest opens a fresh in-memory database. Every call gets its own
+// database, so test packages and tests never share state.
 func ConnectTest() (*sql.DB, error) {
-	var err error
-	Db, err = sql.Open("sqlite3", "file::memory:?cache=shared&mode=rwc&_journal_mode=WAL&busy_timeout=10000")
-	Db.SetMaxOpenConns(1)
+	return open(fmt.Sprintf("file:test-%s?mode=memory&cache=shared&_journal_mode=WAL&busy_timeout=10000", uuid.NewString()))
+}
+
+func open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, err
 	}
-	return Db, nil
+	db.SetMaxOpenConns(1)
+	return db, nil
 }
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is go-sqlite3 with foreign keys enforced on every connection.
+// Search needs FTS5, so build and test with -tags sqlite_fts5.
+const driverName = "sqlite3_fire_go"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// SQLite leaves foreign keys off unless each connection asks,
+			// and without them ON DELETE CASCADE does nothing
+			_, err := conn.Exec("PRAGMA foreign_keys = ON", nil)
+			return err
+		},
+	})
+}
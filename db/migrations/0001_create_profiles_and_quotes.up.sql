@@ -0,0 +1,18 @@
+-- IF NOT EXISTS lets databases created before versioned migrations adopt this one.
+CREATE TABLE IF NOT EXISTS profiles (
+	id TEXT PRIMARY KEY,
+	user_id TEXT UNIQUE NOT NULL,
+	email TEXT UNIQUE NOT NULL,
+	username TEXT,
+	bio TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS quotes (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	quote TEXT NOT NULL,
+	approved BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+ALTER TABLE quotes ADD COLUMN approved BOOLEAN DEFAULT FALSE;
+
+UPDATE quotes SET approved = (status = 'approved');
+
+DROP INDEX idx_quotes_status;
+ALTER TABLE quotes DROP COLUMN rejection_reason;
+ALTER TABLE quotes DROP COLUMN reviewed_at;
+ALTER TABLE quotes DROP COLUMN reviewed_by;
+ALTER TABLE quotes DROP COLUMN status;
@@ -0,0 +1,11 @@
+ALTER TABLE quotes ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+	CHECK (status IN ('draft', 'pending', 'approved', 'rejected', 'withdrawn'));
+ALTER TABLE quotes ADD COLUMN reviewed_by TEXT;
+ALTER TABLE quotes ADD COLUMN reviewed_at TIMESTAMP;
+ALTER TABLE quotes ADD COLUMN rejection_reason TEXT;
+
+UPDATE quotes SET status = 'approved' WHERE approved;
+
+ALTER TABLE quotes DROP COLUMN approved;
+
+CREATE INDEX idx_quotes_status ON quotes (status);
@@ -0,0 +1,29 @@
+CREATE TABLE quote_revisions (
+	id TEXT PRIMARY KEY,
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	quote TEXT NOT NULL,
+	edited_by TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('draft', 'pending', 'approved', 'rejected')),
+	reviewed_by TEXT,
+	reviewed_at TIMESTAMP,
+	rejection_reason TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (quote_id, revision)
+);
+
+-- The current text of every existing quote becomes its first revision.
+INSERT INTO quote_revisions (id, quote_id, revision, quote, edited_by, status, reviewed_by, reviewed_at, rejection_reason, created_at)
+SELECT
+	lower(hex(randomblob(16))),
+	id,
+	1,
+	quote,
+	user_id,
+	CASE WHEN status = 'withdrawn' THEN 'pending' ELSE status END,
+	reviewed_by,
+	reviewed_at,
+	rejection_reason,
+	COALESCE(updated_at, created_at)
+FROM quotes;
@@ -0,0 +1,23 @@
+CREATE TABLE quote_revisions_old (
+	id TEXT PRIMARY KEY,
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	quote TEXT NOT NULL,
+	edited_by TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('draft', 'pending', 'approved', 'rejected')),
+	reviewed_by TEXT,
+	reviewed_at TIMESTAMP,
+	rejection_reason TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (quote_id, revision)
+);
+
+INSERT INTO quote_revisions_old
+SELECT id, quote_id, revision, quote, edited_by,
+	CASE WHEN status = 'superseded' THEN 'pending' ELSE status END,
+	reviewed_by, reviewed_at, rejection_reason, created_at
+FROM quote_revisions;
+
+DROP TABLE quote_revisions;
+ALTER TABLE quote_revisions_old RENAME TO quote_revisions;
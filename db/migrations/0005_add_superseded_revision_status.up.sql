@@ -0,0 +1,27 @@
+-- SQLite cannot change a CHECK constraint in place, so the table is rebuilt.
+CREATE TABLE quote_revisions_new (
+	id TEXT PRIMARY KEY,
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	quote TEXT NOT NULL,
+	edited_by TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('draft', 'pending', 'approved', 'rejected', 'superseded')),
+	reviewed_by TEXT,
+	reviewed_at TIMESTAMP,
+	rejection_reason TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (quote_id, revision)
+);
+
+INSERT INTO quote_revisions_new SELECT * FROM quote_revisions;
+
+-- Unreviewed revisions that a later edit replaced.
+UPDATE quote_revisions_new SET status = 'superseded'
+WHERE status IN ('draft', 'pending')
+	AND revision < (SELECT MAX(r.revision) FROM quote_revisions_new r WHERE r.quote_id = quote_revisions_new.quote_id);
+
+DROP TABLE quote_revisions;
+ALTER TABLE quote_revisions_new RENAME TO quote_revisions;
+
+CREATE INDEX idx_quote_revisions_pending ON quote_revisions (quote_id) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+DROP INDEX idx_profiles_created_at;
+DROP INDEX idx_quotes_user_id_created_at;
+DROP INDEX idx_quotes_created_at;
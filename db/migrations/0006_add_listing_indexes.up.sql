@@ -0,0 +1,3 @@
+CREATE INDEX idx_quotes_created_at ON quotes (created_at, id);
+CREATE INDEX idx_quotes_user_id_created_at ON quotes (user_id, created_at, id);
+CREATE INDEX idx_profiles_created_at ON profiles (created_at, id);
@@ -0,0 +1,21 @@
+-- Needs go-sqlite3's sqlite_fts5 build tag. Ranking uses FTS5's built-in bm25().
+CREATE VIRTUAL TABLE quotes_fts USING fts5(
+	quote_id UNINDEXED,
+	quote,
+	tokenize = 'unicode61 remove_diacritics 1'
+);
+
+CREATE TRIGGER quotes_fts_insert AFTER INSERT ON quotes BEGIN
+	INSERT INTO quotes_fts (quote_id, quote) VALUES (new.id, new.quote);
+END;
+
+CREATE TRIGGER quotes_fts_update AFTER UPDATE OF quote ON quotes BEGIN
+	DELETE FROM quotes_fts WHERE quote_id = old.id;
+	INSERT INTO quotes_fts (quote_id, quote) VALUES (new.id, new.quote);
+END;
+
+CREATE TRIGGER quotes_fts_delete AFTER DELETE ON quotes BEGIN
+	DELETE FROM quotes_fts WHERE quote_id = old.id;
+END;
+
+INSERT INTO quotes_fts (quote_id, quote) SELECT id, quote FROM quotes;
@@ -0,0 +1,3 @@
+ALTER TABLE quote_revisions DROP COLUMN tags;
+DROP TABLE quote_tags;
+DROP TABLE tags;
@@ -0,0 +1,18 @@
+CREATE TABLE tags (
+	id TEXT PRIMARY KEY,
+	slug TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The tags of each quote's current text. Like quotes.quote, they only change
+-- once a revision is accepted.
+CREATE TABLE quote_tags (
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	tag_id TEXT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (quote_id, tag_id)
+);
+
+CREATE INDEX idx_quote_tags_tag_id ON quote_tags (tag_id);
+
+-- Comma-separated tag slugs proposed with each revision.
+ALTER TABLE quote_revisions ADD COLUMN tags TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+ALTER TABLE quote_revisions DROP COLUMN source_page;
+ALTER TABLE quote_revisions DROP COLUMN source_id;
+ALTER TABLE quote_revisions DROP COLUMN author_id;
+DROP INDEX idx_quotes_author_id_created_at;
+ALTER TABLE quotes DROP COLUMN source_page;
+ALTER TABLE quotes DROP COLUMN source_id;
+ALTER TABLE quotes DROP COLUMN author_id;
+DROP TABLE sources;
+DROP TABLE authors;
@@ -0,0 +1,32 @@
+-- People a quote is attributed to, as opposed to the member who submitted it.
+-- name_key is the name lowercased with punctuation and extra spaces removed,
+-- so that "Mark  Twain" and "mark twain" are the same author.
+CREATE TABLE authors (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	name_key TEXT UNIQUE NOT NULL,
+	born INTEGER,
+	died INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Works by an author that quotes are taken from.
+CREATE TABLE sources (
+	id TEXT PRIMARY KEY,
+	author_id TEXT NOT NULL REFERENCES authors (id),
+	title TEXT NOT NULL,
+	title_key TEXT NOT NULL,
+	year INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (author_id, title_key)
+);
+
+ALTER TABLE quotes ADD COLUMN author_id TEXT REFERENCES authors (id);
+ALTER TABLE quotes ADD COLUMN source_id TEXT REFERENCES sources (id);
+ALTER TABLE quotes ADD COLUMN source_page TEXT;
+
+CREATE INDEX idx_quotes_author_id_created_at ON quotes (author_id, created_at, id);
+
+ALTER TABLE quote_revisions ADD COLUMN author_id TEXT;
+ALTER TABLE quote_revisions ADD COLUMN source_id TEXT;
+ALTER TABLE quote_revisions ADD COLUMN source_page TEXT;
@@ -0,0 +1,4 @@
+DROP TABLE quote_minhash_bands;
+DROP INDEX idx_quotes_content_hash;
+ALTER TABLE quotes DROP COLUMN minhash;
+ALTER TABLE quotes DROP COLUMN content_hash;
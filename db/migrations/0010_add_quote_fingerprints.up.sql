@@ -0,0 +1,17 @@
+-- Fingerprints for duplicate detection, see quote/duplicate.go. Quotes created
+-- before this migration are fingerprinted by the server on start.
+ALTER TABLE quotes ADD COLUMN content_hash TEXT;
+ALTER TABLE quotes ADD COLUMN minhash BLOB;
+
+CREATE INDEX idx_quotes_content_hash ON quotes (content_hash);
+
+-- One row per band of each quote's MinHash signature. Quotes that share a band
+-- are candidates for being near duplicates.
+CREATE TABLE quote_minhash_bands (
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	band INTEGER NOT NULL,
+	hash INTEGER NOT NULL,
+	PRIMARY KEY (quote_id, band)
+);
+
+CREATE INDEX idx_quote_minhash_bands_hash ON quote_minhash_bands (band, hash);
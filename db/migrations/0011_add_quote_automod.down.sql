@@ -0,0 +1,4 @@
+DROP INDEX idx_quotes_automod_risk;
+ALTER TABLE quotes DROP COLUMN automod_reasons;
+ALTER TABLE quotes DROP COLUMN automod_risk;
+ALTER TABLE quotes DROP COLUMN automod_decision;
@@ -0,0 +1,8 @@
+-- The automatic moderation verdict on each quote's latest submitted text, see
+-- the automod package. automod_decision is NULL for quotes submitted before
+-- automatic moderation existed.
+ALTER TABLE quotes ADD COLUMN automod_decision TEXT CHECK (automod_decision IN ('allow', 'flag', 'reject'));
+ALTER TABLE quotes ADD COLUMN automod_risk REAL NOT NULL DEFAULT 0;
+ALTER TABLE quotes ADD COLUMN automod_reasons TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_quotes_automod_risk ON quotes (automod_risk, created_at, id);
@@ -0,0 +1,3 @@
+DROP TABLE spam_tokens;
+DROP TABLE spam_model;
+ALTER TABLE quotes DROP COLUMN automod_scores;
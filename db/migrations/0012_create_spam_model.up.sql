@@ -0,0 +1,23 @@
+-- Named scores from automatic moderation, such as the spam classifier's, as a
+-- JSON object.
+ALTER TABLE quotes ADD COLUMN automod_scores TEXT NOT NULL DEFAULT '';
+
+-- The naive Bayes spam model, see the spam package. spam_model holds the
+-- single row of totals and spam_tokens how many approved (ham) and rejected
+-- (spam) texts each token appeared in.
+CREATE TABLE spam_model (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	ham_docs INTEGER NOT NULL DEFAULT 0,
+	spam_docs INTEGER NOT NULL DEFAULT 0,
+	ham_tokens INTEGER NOT NULL DEFAULT 0,
+	spam_tokens INTEGER NOT NULL DEFAULT 0,
+	trained_at DATETIME
+);
+
+INSERT INTO spam_model (id) VALUES (1);
+
+CREATE TABLE spam_tokens (
+	token TEXT PRIMARY KEY,
+	ham INTEGER NOT NULL DEFAULT 0,
+	spam INTEGER NOT NULL DEFAULT 0
+);
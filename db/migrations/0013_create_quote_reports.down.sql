@@ -0,0 +1,2 @@
+DROP TABLE quote_reports;
+ALTER TABLE quotes DROP COLUMN hidden_at;
@@ -0,0 +1,20 @@
+-- Set while a published quote is back in the moderation queue because
+-- readers reported it.
+ALTER TABLE quotes ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE quote_reports (
+	id TEXT PRIMARY KEY,
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL,
+	reason TEXT NOT NULL
+		CHECK (reason IN ('spam', 'offensive', 'misattributed', 'duplicate', 'copyright', 'other')),
+	details TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'upheld')),
+	created_at TIMESTAMP NOT NULL,
+	resolved_by TEXT,
+	resolved_at TIMESTAMP
+);
+
+-- A reader has at most one open report per quote.
+CREATE UNIQUE INDEX idx_quote_reports_open_user ON quote_reports (quote_id, user_id) WHERE status = 'open';
+CREATE INDEX idx_quote_reports_status_quote ON quote_reports (status, quote_id);
@@ -0,0 +1,2 @@
+ALTER TABLE quotes DROP COLUMN like_count;
+DROP TABLE quote_likes;
@@ -0,0 +1,12 @@
+CREATE TABLE quote_likes (
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (quote_id, user_id)
+);
+
+CREATE INDEX idx_quote_likes_user_id ON quote_likes (user_id);
+
+-- A counter kept in step with quote_likes as likes come and go, so listings
+-- do not count likes per row. The server reconciles it periodically.
+ALTER TABLE quotes ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE comment_threads;
+DROP TABLE comments;
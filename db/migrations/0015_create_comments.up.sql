@@ -0,0 +1,24 @@
+-- Comments on quotes, see the comment package. parent_id is NULL for
+-- top-level comments; replies only go one level deep. Deleted comments keep
+-- their row so replies stay in place.
+CREATE TABLE comments (
+	id TEXT PRIMARY KEY,
+	quote_id TEXT NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+	parent_id TEXT REFERENCES comments (id),
+	user_id TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	edited_at TIMESTAMP,
+	deleted_at TIMESTAMP,
+	deleted_by TEXT
+);
+
+CREATE INDEX idx_comments_quote_id_created_at ON comments (quote_id, created_at, id);
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+
+-- A row exists once an admin has locked a quote's comments.
+CREATE TABLE comment_threads (
+	quote_id TEXT PRIMARY KEY REFERENCES quotes (id) ON DELETE CASCADE,
+	locked_at TIMESTAMP NOT NULL,
+	locked_by TEXT NOT NULL
+);
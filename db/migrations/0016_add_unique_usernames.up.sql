@@ -0,0 +1,10 @@
+-- Generated usernames used to collide. Give every duplicate but the oldest a
+-- suffix from its profile ID before enforcing uniqueness.
+UPDATE profiles SET username = username || substr(id, 1, 8)
+WHERE EXISTS (
+	SELECT 1 FROM profiles p
+	WHERE p.username = profiles.username COLLATE NOCASE
+		AND (p.created_at < profiles.created_at OR (p.created_at = profiles.created_at AND p.id < profiles.id))
+);
+
+CREATE UNIQUE INDEX idx_profiles_username ON profiles (username COLLATE NOCASE);
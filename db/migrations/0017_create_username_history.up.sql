@@ -0,0 +1,9 @@
+-- Usernames a user has given up, so links to old handles can redirect to the
+-- current one. A live username always takes precedence over a row here.
+CREATE TABLE username_history (
+	username TEXT PRIMARY KEY COLLATE NOCASE,
+	user_id TEXT NOT NULL,
+	changed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_username_history_user_id ON username_history (user_id);
@@ -0,0 +1,10 @@
+-- Every time a profile's email is brought in line with the user's token.
+CREATE TABLE profile_email_changes (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	old_email TEXT NOT NULL,
+	new_email TEXT NOT NULL,
+	changed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_profile_email_changes_user_id ON profile_email_changes (user_id, changed_at);
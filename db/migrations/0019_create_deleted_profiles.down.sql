@@ -0,0 +1 @@
+DROP TABLE deleted_profiles;
@@ -0,0 +1,6 @@
+-- Users whose profile was deleted on purpose. Automatic provisioning leaves
+-- them alone until they create a profile again themselves.
+CREATE TABLE deleted_profiles (
+	user_id TEXT PRIMARY KEY,
+	deleted_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var testDb *sql.DB
+
+func TestMain(m *testing.M) {
+	var err error
+	testDb, err = ConnectTest()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer testDb.Close()
+
+	os.Exit(m.Run())
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"m/0001_create_things.up.sql":   {Data: []byte("CREATE TABLE things (id TEXT PRIMARY KEY);")},
+		"m/0001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+		"m/0002_add_name.up.sql":        {Data: []byte("ALTER TABLE things ADD COLUMN name TEXT;")},
+		"m/0002_add_name.down.sql":      {Data: []byte("ALTER TABLE things DROP COLUMN name;")},
+	}
+}
+
+func resetMigrations(t *testing.T) {
+	t.Helper()
+	for _, stmt := range []string{"DROP TABLE IF EXISTS things", "DROP TABLE IF EXISTS schema_migrations"} {
+		if _, err := testDb.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	resetMigrations(t)
+	ctx := context.Background()
+	m, err := newMigrator(testDb, testMigrations(), "m")
+	if err != nil {
+		t.Fatalf("newMigrator error: %v", err)
+	}
+
+	// Test case 1: up applies everything
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up error: %v", err)
+	}
+	if _, err := testDb.Exec("INSERT INTO things (id, name) VALUES ('1', 'one')"); err != nil {
+		t.Errorf("Up error: schema not applied: %v", err)
+	}
+	version, _ := m.Version(ctx)
+	if version != 2 {
+		t.Errorf("Version error: expected 2, got %d", version)
+	}
+
+	// Test case 2: up again is a no-op
+	if err := m.Up(ctx); err != nil {
+		t.Errorf("Up error on second run: %v", err)
+	}
+
+	// Test case 3: down reverts one step
+	if err := m.Down(ctx); err != nil {
+		t.Fatalf("Down error: %v", err)
+	}
+	if _, err := testDb.Exec("SELECT name FROM things"); err == nil {
+		t.Error("Down error: column still exists")
+	}
+	statuses, _ := m.Status(ctx)
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("Status error: unexpected %+v", statuses)
+	}
+
+	// Test case 4: to 0 reverts everything
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To error: %v", err)
+	}
+	if _, err := testDb.Exec("SELECT id FROM things"); err == nil {
+		t.Error("To error: table still exists")
+	}
+}
+
+func TestMigratorDrift(t *testing.T) {
+	resetMigrations(t)
+	ctx := context.Background()
+	m, _ := newMigrator(testDb, testMigrations(), "m")
+	if err := m.To(ctx, 1); err != nil {
+		t.Fatalf("To error: %v", err)
+	}
+
+	// Edit an applied migration
+	files := testMigrations()
+	files["m/0001_create_things.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE things (id INTEGER PRIMARY KEY);")}
+	edited, _ := newMigrator(testDb, files, "m")
+
+	err := edited.Up(ctx)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Up error: expected ErrChecksumMismatch, got %v", err)
+	}
+	statuses, _ := edited.Status(ctx)
+	if !statuses[0].Drifted {
+		t.Error("Status error: drift not reported")
+	}
+}
+
+func TestMigratorRollsBackFailedMigration(t *testing.T) {
+	resetMigrations(t)
+	ctx := context.Background()
+	files := testMigrations()
+	files["m/0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE more (id TEXT); SELECT * FROM missing;")}
+	m, _ := newMigrator(testDb, files, "m")
+
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("Up error: broken migration succeeded")
+	}
+	version, _ := m.Version(ctx)
+	if version != 2 {
+		t.Errorf("Version error: expected 2, got %d", version)
+	}
+	if _, err := testDb.Exec("SELECT id FROM more"); err == nil {
+		t.Error("Up error: partial migration was not rolled back")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	resetMigrations(t)
+	_, _ = testDb.Exec("DROP TABLE IF EXISTS quotes")
+	_, _ = testDb.Exec("DROP TABLE IF EXISTS profiles")
+	ctx := context.Background()
+	m, err := NewMigrator(testDb)
+	if err != nil {
+		t.Fatalf("NewMigrator error: %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up error: %v", err)
+	}
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To 0 error: %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after full revert error: %v", err)
+	}
+}
+
+func TestForeignKeysCascade(t *testing.T) {
+	conn, err := ConnectTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	m, err := NewMigrator(conn)
+	if err != nil {
+		t.Fatalf("NewMigrator error: %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up error: %v", err)
+	}
+
+	children := []string{
+		"INSERT INTO quote_revisions (id, quote_id, revision, quote, edited_by) VALUES ('r1', 'q1', 1, 'text', 'u1')",
+		"INSERT INTO quote_tags (quote_id, tag_id) VALUES ('q1', 't1')",
+		"INSERT INTO quote_minhash_bands (quote_id, band, hash) VALUES ('q1', 0, 42)",
+		"INSERT INTO quote_reports (id, quote_id, user_id, reason, created_at) VALUES ('rp1', 'q1', 'u2', 'spam', CURRENT_TIMESTAMP)",
+		"INSERT INTO quote_likes (quote_id, user_id, created_at) VALUES ('q1', 'u2', CURRENT_TIMESTAMP)",
+		"INSERT INTO comments (id, quote_id, user_id, body, created_at) VALUES ('c1', 'q1', 'u2', 'body', CURRENT_TIMESTAMP)",
+		"INSERT INTO comments (id, quote_id, parent_id, user_id, body, created_at) VALUES ('c2', 'q1', 'c1', 'u1', 'reply', CURRENT_TIMESTAMP)",
+		"INSERT INTO comment_threads (quote_id, locked_at, locked_by) VALUES ('q1', CURRENT_TIMESTAMP, 'admin1')",
+	}
+	setup := append([]string{
+		"INSERT INTO authors (id, name, name_key) VALUES ('a1', 'Author', 'author')",
+		"INSERT INTO quotes (id, user_id, quote, status, author_id) VALUES ('q1', 'u1', 'text', 'approved', 'a1')",
+		"INSERT INTO tags (id, slug) VALUES ('t1', 'tag')",
+	}, children...)
+	for _, stmt := range setup {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	// Test case 1: child rows must point at an existing parent
+	if _, err := conn.Exec("INSERT INTO quote_likes (quote_id, user_id, created_at) VALUES ('missing', 'u2', CURRENT_TIMESTAMP)"); err == nil {
+		t.Errorf("foreign keys error: expected an orphaned like to be refused")
+	}
+
+	// Test case 2: deleting a quote deletes every row that references it
+	if _, err := conn.Exec("DELETE FROM quotes WHERE id = 'q1'"); err != nil {
+		t.Fatalf("DELETE quote error: %v", err)
+	}
+	for _, table := range []string{"quote_revisions", "quote_tags", "quote_minhash_bands", "quote_reports", "quote_likes", "comments", "comment_threads"} {
+		var n int
+		if err := conn.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("foreign keys error: expected %s to be emptied, %d rows left", table, n)
+		}
+	}
+
+	// Test case 3: migrations still revert with related rows in place
+	for _, stmt := range setup {
+		if _, err := conn.Exec(stmt); err != nil && !strings.Contains(err.Error(), "UNIQUE") {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To 0 error: %v", err)
+	}
+}
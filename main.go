@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"log"
 	"log/slog"
 	"os"
 
-	"firebase.google.com/go/v4/auth"
-
+	"github.com/cprime50/fire-go/automod"
+	"github.com/cprime50/fire-go/comment"
 	"github.com/cprime50/fire-go/db"
+	"github.com/cprime50/fire-go/policy"
 	"github.com/cprime50/fire-go/role"
+	"github.com/cprime50/fire-go/spam"
 
 	"github.com/cprime50/fire-go/middleware"
 	"github.com/cprime50/fire-go/profile"
@@ -19,13 +24,15 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// devMode starts the API against the Firebase Auth Emulator, so no service
+// account or .env file is needed.
+var devMode = flag.Bool("dev", false, "use the Firebase Auth Emulator instead of a Firebase project")
+
 func main() {
+	flag.Parse()
 	loadEnv()
-
-	// Initialize Firebase authentication middleware
-	client, err := middleware.InitAuth()
-	if err != nil {
-		log.Fatalf("Error initializing Firebase auth: %v", err)
+	if *devMode {
+		setupDevMode()
 	}
 
 	//Connect db
@@ -35,21 +42,65 @@ func main() {
 		log.Fatal("Error connecting to Db", err)
 	}
 	log.Println("Database connected successfully")
+	defer Db.Close()
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(context.Background(), Db, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	// migrations
 	log.Printf("Migrations Started")
-	err = db.Migrate(Db)
+	migrator, err := db.NewMigrator(Db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if n, err := quote.NewQuoteRepository(Db).BackfillFingerprints(context.Background()); err != nil {
+		log.Fatal(err)
+	} else if n > 0 {
+		log.Printf("Fingerprinted %d quotes for duplicate detection", n)
+	}
+	go quote.ReconcileLikes(context.Background(), quote.NewQuoteRepository(Db), quote.LikeReconcileInterval)
+
+	// Initialize Firebase (or local JWKS) authentication middleware
+	client, err := middleware.InitAuth()
+	if err != nil {
+		log.Fatalf("Error initializing Firebase auth: %v", err)
+	}
+
+	// Role to permission mapping
+	pol, err := policy.Load(os.Getenv("RBAC_POLICY_FILE"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Db.Close()
+
+	// Automatic moderation rules for quote submissions
+	mod, err := automod.Load(os.Getenv("AUTOMOD_CONFIG"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	classifier, err := spam.NewClassifier(context.Background(), spam.NewSpamRepository(Db))
+	if err != nil {
+		log.Fatal(err)
+	}
+	mod.Chain = append(mod.Chain, classifier)
 
 	r := gin.Default()
 	r.Use(cors.Default())
 
+	// Profiles are created on a user's first authenticated request. The
+	// provisioner remembers who has one, so it is shared by every route.
+	profiles := profile.NewProvisioner(profile.NewProfileService(profile.NewProfileRepository(Db)))
+
 	// Register routes
-	RegisterRoutes(r, client)
-	RegisterAdminRoutes(r, client)
+	RegisterRoutes(r, client, pol, mod, profiles, Db)
+	RegisterAdminRoutes(r, client, pol, mod, classifier, profiles, Db)
+	RegisterModerationRoutes(r, client, pol, mod, profiles, Db)
 
 	// Set port
 	port := os.Getenv("PORT")
@@ -65,35 +116,71 @@ func main() {
 func loadEnv() {
 	err := godotenv.Load("./.env")
 	if err != nil {
+		if *devMode || flag.Arg(0) == "migrate" {
+			log.Println("No .env file found, continuing without it")
+			return
+		}
 		log.Fatal("Error loading .env file", err)
 	}
 	log.Println(".env file loaded successfully")
 }
 
-func RegisterRoutes(r *gin.Engine, client *auth.Client) {
-	s := profile.ProfileServiceImpl{} // Corrected instantiation
+func setupDevMode() {
+	if os.Getenv(middleware.EmulatorHostEnv) == "" {
+		os.Setenv(middleware.EmulatorHostEnv, "localhost:9099")
+	}
+	// Local JWKS verification would take precedence over the emulator
+	os.Unsetenv("AUTH_JWKS")
+	log.Printf("Dev mode: using the Firebase Auth Emulator at %s for project %s",
+		os.Getenv(middleware.EmulatorHostEnv), middleware.EmulatorProjectID())
+}
+
+func RegisterRoutes(r *gin.Engine, client middleware.AuthClient, pol *policy.Policy, mod *automod.Pipeline, s *profile.Provisioner, conn *sql.DB) {
+	// Explicit creation skips provisioning, so it still reports whether the
+	// profile is new
+	r.POST("/profile/create", middleware.Auth(client, pol), func(c *gin.Context) {
+		profile.CreateProfileHandler(c, s)
+	})
 
 	profileRoutes := r.Group("/profile")
-	profileRoutes.Use(middleware.Auth(client))
+	profileRoutes.Use(middleware.Auth(client, pol), middleware.Provision(s))
 	{
-		profileRoutes.POST("/create", func(c *gin.Context) {
-			profile.CreateProfileHandler(c, s)
-		})
 		profileRoutes.PUT("/update", func(c *gin.Context) {
 			profile.UpdateProfileHandler(c, s)
 		})
+		profileRoutes.GET("/username-available", func(c *gin.Context) {
+			profile.UsernameAvailableHandler(c, s)
+		})
 		profileRoutes.DELETE("/delete/:id", func(c *gin.Context) {
 			profile.DeleteProfileHandler(c, s)
 		})
-		profileRoutes.GET("/:id", func(c *gin.Context) {
+	}
+
+	quoteService := quote.NewQuoteService(quote.NewQuoteRepository(conn), mod)
+	commentService := comment.NewCommentService(comment.NewCommentRepository(conn))
+
+	// Public profile pages need no sign-in
+	r.GET("/u/:username", func(c *gin.Context) {
+		profile.GetPublicProfileHandler(c, s, quoteService)
+	})
+
+	// Reads that anonymous visitors may make; they only see approved content
+	publicRoutes := r.Group("/")
+	publicRoutes.Use(middleware.OptionalAuth(client, pol), middleware.Provision(s))
+	{
+		publicRoutes.GET("/profile/:id", func(c *gin.Context) {
 			profile.GetProfileHandler(c, s)
 		})
+		publicRoutes.GET("/quote/", func(c *gin.Context) {
+			quote.GetQuotesHandler(c, quoteService)
+		})
+		publicRoutes.GET("/quote/quotes/:profile-id", func(c *gin.Context) {
+			quote.GetQuotesByUserIdHandler(c, quoteService)
+		})
 	}
 
-	quoteService := &quote.QuoteServiceImpl{}
-
 	quoteRoutes := r.Group("/quote")
-	quoteRoutes.Use(middleware.Auth(client))
+	quoteRoutes.Use(middleware.Auth(client, pol), middleware.Provision(s))
 	{
 		quoteRoutes.POST("/create", func(c *gin.Context) {
 			quote.CreateQuoteHandler(c, quoteService)
@@ -104,46 +191,169 @@ func RegisterRoutes(r *gin.Engine, client *auth.Client) {
 		quoteRoutes.DELETE("/delete/:id", func(c *gin.Context) {
 			quote.DeleteQuoteHandler(c, quoteService)
 		})
-		quoteRoutes.GET("/", func(c *gin.Context) {
-			quote.GetQuotesHandler(c, quoteService)
+		quoteRoutes.GET("/search", func(c *gin.Context) {
+			quote.SearchQuotesHandler(c, quoteService)
 		})
-		quoteRoutes.GET("/quotes/:profile-id", func(c *gin.Context) {
-			quote.GetQuotesByUserIdHandler(c, quoteService)
+		quoteRoutes.GET("/tags", func(c *gin.Context) {
+			quote.GetTagsHandler(c, quoteService)
+		})
+		quoteRoutes.GET("/tags/:slug", func(c *gin.Context) {
+			quote.GetQuotesByTagHandler(c, quoteService)
 		})
-		quoteRoutes.PUT("/approve/:id", func(c *gin.Context) {
+		quoteRoutes.PUT("/approve/:id", middleware.RequirePermission(policy.QuoteApprove), func(c *gin.Context) {
 			quote.ApproveQuoteHandler(c, quoteService)
 		})
-		quoteRoutes.GET("/unapproved", func(c *gin.Context) {
+		quoteRoutes.PUT("/reject/:id", middleware.RequirePermission(policy.QuoteApprove), func(c *gin.Context) {
+			quote.RejectQuoteHandler(c, quoteService)
+		})
+		quoteRoutes.PUT("/submit/:id", func(c *gin.Context) {
+			quote.SubmitQuoteHandler(c, quoteService)
+		})
+		quoteRoutes.PUT("/withdraw/:id", func(c *gin.Context) {
+			quote.WithdrawQuoteHandler(c, quoteService)
+		})
+		quoteRoutes.GET("/unapproved", middleware.RequirePermission(policy.QuoteApprove), func(c *gin.Context) {
 			quote.GetUnapprovedQuotesHandler(c, quoteService)
 		})
+		quoteRoutes.GET("/revisions/:id", func(c *gin.Context) {
+			quote.GetRevisionsHandler(c, quoteService)
+		})
+		quoteRoutes.GET("/diff/:id", func(c *gin.Context) {
+			quote.GetRevisionDiffHandler(c, quoteService)
+		})
+		quoteRoutes.POST("/:id/report", middleware.RequirePermission(policy.QuoteReport), func(c *gin.Context) {
+			quote.ReportQuoteHandler(c, quoteService)
+		})
+		quoteRoutes.PUT("/:id/like", middleware.RequirePermission(policy.QuoteLike), func(c *gin.Context) {
+			quote.LikeQuoteHandler(c, quoteService)
+		})
+		quoteRoutes.DELETE("/:id/like", middleware.RequirePermission(policy.QuoteLike), func(c *gin.Context) {
+			quote.UnlikeQuoteHandler(c, quoteService)
+		})
+		quoteRoutes.GET("/liked", func(c *gin.Context) {
+			quote.GetLikedQuotesHandler(c, quoteService)
+		})
+		quoteRoutes.GET("/:id/comments", func(c *gin.Context) {
+			comment.GetThreadHandler(c, commentService)
+		})
+		quoteRoutes.POST("/:id/comments", middleware.RequirePermission(policy.CommentCreate), func(c *gin.Context) {
+			comment.CreateCommentHandler(c, commentService)
+		})
+	}
+
+	commentRoutes := r.Group("/comments")
+	commentRoutes.Use(middleware.Auth(client, pol), middleware.Provision(s))
+	{
+		commentRoutes.PUT("/:id", func(c *gin.Context) {
+			comment.UpdateCommentHandler(c, commentService)
+		})
+		commentRoutes.DELETE("/:id", func(c *gin.Context) {
+			comment.DeleteCommentHandler(c, commentService)
+		})
+	}
+
+	authorRoutes := r.Group("/authors")
+	authorRoutes.Use(middleware.Auth(client, pol), middleware.Provision(s))
+	{
+		authorRoutes.GET("/", func(c *gin.Context) {
+			quote.GetAuthorsHandler(c, quoteService)
+		})
+		authorRoutes.GET("/:id", func(c *gin.Context) {
+			quote.GetAuthorHandler(c, quoteService)
+		})
+		authorRoutes.GET("/:id/quotes", func(c *gin.Context) {
+			quote.GetQuotesByAuthorHandler(c, quoteService)
+		})
 	}
 }
 
 // Admin routes
-func RegisterAdminRoutes(r *gin.Engine, client *auth.Client) {
-	profileService := profile.ProfileServiceImpl{}
-	quoteService := &quote.QuoteServiceImpl{}
+func RegisterAdminRoutes(r *gin.Engine, client middleware.AuthClient, pol *policy.Policy, mod *automod.Pipeline, classifier *spam.Classifier, profileService *profile.Provisioner, conn *sql.DB) {
+	quoteService := quote.NewQuoteService(quote.NewQuoteRepository(conn), mod)
 	adminService := role.NewAdminService(client)
+	spamService := spam.NewSpamService(classifier)
+	commentService := comment.NewCommentService(comment.NewCommentRepository(conn))
 
 	adminRoutes := r.Group("/admin")
-	adminRoutes.Use(middleware.Auth(client), middleware.RoleAuth("admin"))
+	adminRoutes.Use(middleware.Auth(client, pol), middleware.Provision(profileService))
 	{
-		adminRoutes.GET("/profiles", func(c *gin.Context) {
+		adminRoutes.GET("/profiles", middleware.RequirePermission(policy.ProfileRead), func(c *gin.Context) {
 			profile.GetAllProfilesHandler(c, profileService)
 		})
 		// Update this line to use the separated handler for approving quotes
-		adminRoutes.POST("/quote/approve/:id", func(c *gin.Context) {
+		adminRoutes.POST("/quote/approve/:id", middleware.RequirePermission(policy.QuoteApprove), func(c *gin.Context) {
 			quote.ApproveQuoteHandler(c, quoteService)
 		})
+		adminRoutes.POST("/quote/reject/:id", middleware.RequirePermission(policy.QuoteApprove), func(c *gin.Context) {
+			quote.RejectQuoteHandler(c, quoteService)
+		})
 		// Update this line to use the separated handler for getting unapproved quotes
-		adminRoutes.GET("/quote/unapproved", func(c *gin.Context) {
+		adminRoutes.GET("/quote/unapproved", middleware.RequirePermission(policy.QuoteApprove), func(c *gin.Context) {
 			quote.GetUnapprovedQuotesHandler(c, quoteService)
 		})
-		adminRoutes.POST("/make", func(ctx *gin.Context) {
+		adminRoutes.POST("/quote/rollback/:id", middleware.RequirePermission(policy.QuoteUpdate, policy.QuoteApprove), func(c *gin.Context) {
+			quote.RollbackQuoteHandler(c, quoteService)
+		})
+		adminRoutes.PUT("/tags/:slug", middleware.RequirePermission(policy.TagManage), func(c *gin.Context) {
+			quote.RenameTagHandler(c, quoteService)
+		})
+		adminRoutes.POST("/tags/merge", middleware.RequirePermission(policy.TagManage), func(c *gin.Context) {
+			quote.MergeTagsHandler(c, quoteService)
+		})
+		adminRoutes.POST("/authors/merge", middleware.RequirePermission(policy.AuthorManage), func(c *gin.Context) {
+			quote.MergeAuthorsHandler(c, quoteService)
+		})
+		adminRoutes.PUT("/quote/:id/comments/lock", middleware.RequirePermission(policy.CommentLock), func(c *gin.Context) {
+			comment.LockThreadHandler(c, commentService)
+		})
+		adminRoutes.GET("/reports", middleware.RequirePermission(policy.ReportManage), func(c *gin.Context) {
+			quote.GetReportSummariesHandler(c, quoteService)
+		})
+		adminRoutes.GET("/reports/:id", middleware.RequirePermission(policy.ReportManage), func(c *gin.Context) {
+			quote.GetQuoteReportsHandler(c, quoteService)
+		})
+		adminRoutes.POST("/reports/:id/resolve", middleware.RequirePermission(policy.ReportManage, policy.QuoteApprove), func(c *gin.Context) {
+			quote.ResolveReportsHandler(c, quoteService)
+		})
+		adminRoutes.GET("/spam", middleware.RequirePermission(policy.SpamManage), func(c *gin.Context) {
+			spam.GetModelHandler(c, spamService)
+		})
+		adminRoutes.POST("/spam/retrain", middleware.RequirePermission(policy.SpamManage), func(c *gin.Context) {
+			spam.RetrainHandler(c, spamService)
+		})
+		adminRoutes.DELETE("/spam", middleware.RequirePermission(policy.SpamManage), func(c *gin.Context) {
+			spam.ResetHandler(c, spamService)
+		})
+		adminRoutes.POST("/make", middleware.RequirePermission(policy.RoleManage), func(ctx *gin.Context) {
 			role.MakeAdminHandler(ctx, adminService)
 		})
-		adminRoutes.DELETE("/remove", func(ctx *gin.Context) {
+		adminRoutes.DELETE("/remove", middleware.RequirePermission(policy.RoleManage), func(ctx *gin.Context) {
 			role.RemoveAdminHandler(ctx, adminService)
 		})
+		adminRoutes.POST("/moderator", middleware.RequirePermission(policy.RoleManage), func(ctx *gin.Context) {
+			role.MakeModeratorHandler(ctx, adminService)
+		})
+		adminRoutes.DELETE("/moderator", middleware.RequirePermission(policy.RoleManage), func(ctx *gin.Context) {
+			role.RemoveModeratorHandler(ctx, adminService)
+		})
+	}
+}
+
+// Moderation routes, open to anyone who can approve quotes
+func RegisterModerationRoutes(r *gin.Engine, client middleware.AuthClient, pol *policy.Policy, mod *automod.Pipeline, profiles *profile.Provisioner, conn *sql.DB) {
+	quoteService := quote.NewQuoteService(quote.NewQuoteRepository(conn), mod)
+
+	moderationRoutes := r.Group("/moderation")
+	moderationRoutes.Use(middleware.Auth(client, pol), middleware.Provision(profiles), middleware.RequirePermission(policy.QuoteApprove))
+	{
+		moderationRoutes.GET("/quotes/unapproved", func(c *gin.Context) {
+			quote.GetUnapprovedQuotesHandler(c, quoteService)
+		})
+		moderationRoutes.PUT("/quotes/approve/:id", func(c *gin.Context) {
+			quote.ApproveQuoteHandler(c, quoteService)
+		})
+		moderationRoutes.PUT("/quotes/reject/:id", func(c *gin.Context) {
+			quote.RejectQuoteHandler(c, quoteService)
+		})
 	}
 }
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,88 +13,177 @@ import (
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
 
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/policy"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/api/option"
 )
 
+var errNoEmailClaim = errors.New("email claim not found in token")
+
 type User struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// Actor converts the authenticated user into the type the service layer works with.
+func (u *User) Actor() actor.Actor {
+	a := actor.Actor{UID: u.UserID, Email: u.Email, Permissions: u.Permissions}
+	if u.Role != "" {
+		a.Roles = []string{u.Role}
+	}
+	return a
 }
 
-func Auth(client *auth.Client) gin.HandlerFunc {
+// CurrentActor returns the actor set by Auth, if any.
+func CurrentActor(ctx *gin.Context) (actor.Actor, bool) {
+	value, exists := ctx.Get("user")
+	if !exists {
+		return actor.Actor{}, false
+	}
+	user, ok := value.(*User)
+	if !ok || user == nil {
+		return actor.Actor{}, false
+	}
+	return user.Actor(), true
+}
+
+// Auth verifies the bearer token and stores the caller as a *User, with the
+// permissions pol grants to their role.
+func Auth(client AuthClient, pol *policy.Policy) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		startTime := time.Now()
 
 		header := ctx.Request.Header.Get("Authorization")
 		if header == "" {
 			log.Println("Missing Authorization header")
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
 			return
 		}
 		idToken := strings.Split(header, "Bearer ")
 		if len(idToken) != 2 {
 			log.Println("Invalid Authorization header")
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
 			return
 		}
 		tokenID := idToken[1]
 
-		token, err := client.VerifyIDToken(context.Background(), tokenID)
+		token, err := client.VerifyIDToken(ctx.Request.Context(), tokenID)
 		if err != nil {
 			log.Printf("Error verifying token. Error: %v\n", err)
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
 			return
 		}
-		processToken(ctx, client, token)
+		user, err := processToken(ctx.Request.Context(), client, pol, token)
+		if errors.Is(err, errNoEmailClaim) {
+			log.Println("Email claim not found in token")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		ctx.Set("user", user)
 		log.Println("Auth time:", time.Since(startTime))
+		ctx.Next()
+	}
+}
+
+// OptionalAuth is Auth for routes that anonymous callers may also use. A
+// valid bearer token stores the caller as a *User; a missing or invalid one
+// lets the request through with no user, as does a token that cannot be
+// turned into one.
+func OptionalAuth(client AuthClient, pol *policy.Policy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.Request.Header.Get("Authorization")
+		if header == "" {
+			ctx.Next()
+			return
+		}
+		idToken := strings.Split(header, "Bearer ")
+		if len(idToken) != 2 {
+			log.Println("Invalid Authorization header, continuing anonymously")
+			ctx.Next()
+			return
+		}
+
+		token, err := client.VerifyIDToken(ctx.Request.Context(), idToken[1])
+		if err != nil {
+			log.Printf("Error verifying token, continuing anonymously. Error: %v\n", err)
+			ctx.Next()
+			return
+		}
+		user, err := processToken(ctx.Request.Context(), client, pol, token)
+		if err != nil {
+			log.Printf("Error processing token, continuing anonymously. Error: %v\n", err)
+			ctx.Next()
+			return
+		}
+		ctx.Set("user", user)
+		ctx.Next()
 	}
 }
 
-func processToken(ctx *gin.Context, client *auth.Client, token *auth.Token) {
+// processToken builds the *User for a verified token, assigning a role the
+// first time it sees the account. It returns errNoEmailClaim for tokens
+// without an email.
+func processToken(ctx context.Context, client ClaimsStore, pol *policy.Policy, token *auth.Token) (*User, error) {
 	adminEmail := os.Getenv("ADMIN_EMAIL")
 	email, ok := token.Claims["email"].(string)
 	if !ok {
-		log.Println("Email claim not found in token")
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized, Invalid Token"})
-		return
+		return nil, errNoEmailClaim
 	}
 	log.Println("auth email is ", email)
 
 	role, ok := token.Claims["role"].(string)
-	if email == adminEmail && role == "user" || !ok {
-		if err := AssignRole(ctx, client, adminEmail, "admin"); err != nil {
-			log.Printf("Error assigning admin role to %s: %v\n", adminEmail, err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
-			return
+	if email == adminEmail && role != policy.RoleAdmin {
+		if err := AssignRole(ctx, client, adminEmail, policy.RoleAdmin); err != nil {
+			return nil, fmt.Errorf("Error assigning admin role to %s: %w", adminEmail, err)
 		}
-		role = "admin"
-	}
-	if !ok {
-		if err := AssignRole(ctx, client, token.UID, "user"); err != nil {
-			log.Printf("Error assigning user role to %s: %v\n", token.UID, err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
-			return
+		role = policy.RoleAdmin
+	} else if !ok {
+		if err := AssignRole(ctx, client, email, policy.RoleUser); err != nil {
+			return nil, fmt.Errorf("Error assigning user role to %s: %w", email, err)
 		}
-		role = "user"
+		role = policy.RoleUser
 	}
 
 	user := &User{
-		UserID: token.UID,
-		Email:  email,
-		Role:   role,
+		UserID:      token.UID,
+		Email:       email,
+		Role:        role,
+		Permissions: pol.PermissionsFor(role),
 	}
-	ctx.Set("user", user)
 
 	log.Println("Successfully authenticated")
 	log.Printf("Email: %v\n", user.Email)
 	log.Printf("Role: %v\n", user.Role)
-
-	ctx.Next()
+	return user, nil
 }
 
-func InitAuth() (*auth.Client, error) {
+// InitAuth returns a LocalAuth when AUTH_JWKS is set, an emulator client when
+// FIREBASE_AUTH_EMULATOR_HOST is set, and a Firebase client otherwise.
+func InitAuth() (AuthClient, error) {
+	if jwksSource := os.Getenv("AUTH_JWKS"); jwksSource != "" {
+		log.Printf("Verifying tokens locally against %s", jwksSource)
+		issuer, audience := os.Getenv("AUTH_ISSUER"), os.Getenv("AUTH_AUDIENCE")
+		if issuer == "" {
+			issuer = FirebaseIssuer(EmulatorProjectID())
+		}
+		if audience == "" {
+			audience = EmulatorProjectID()
+		}
+		return NewLocalAuth(jwksSource, issuer, audience)
+	}
+	if host := os.Getenv(EmulatorHostEnv); host != "" {
+		log.Printf("Using the Firebase Auth Emulator at %s", host)
+		return NewEmulatorClient(context.Background(), EmulatorProjectID())
+	}
+
 	var firebaseCredFile = os.Getenv("FIREBASE_KEY")
 	opt := option.WithCredentialsFile(firebaseCredFile)
 	app, err := firebase.NewApp(context.Background(), nil, opt)
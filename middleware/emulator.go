@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// EmulatorHostEnv is read by the Firebase SDK itself; when it is set every auth
+// call goes to the emulator and no service account is needed.
+const EmulatorHostEnv = "FIREBASE_AUTH_EMULATOR_HOST"
+
+// defaultEmulatorProject uses the "demo-" prefix, which the emulator suite treats
+// as a project that has no real Firebase resources behind it.
+const defaultEmulatorProject = "demo-fire-go"
+
+// EmulatorProjectID returns FIREBASE_PROJECT_ID, falling back to GCLOUD_PROJECT
+// and then to a demo project.
+func EmulatorProjectID() string {
+	if id := os.Getenv("FIREBASE_PROJECT_ID"); id != "" {
+		return id
+	}
+	if id := os.Getenv("GCLOUD_PROJECT"); id != "" {
+		return id
+	}
+	return defaultEmulatorProject
+}
+
+// NewEmulatorClient creates an auth client for the emulator at FIREBASE_AUTH_EMULATOR_HOST.
+func NewEmulatorClient(ctx context.Context, projectID string) (*auth.Client, error) {
+	if os.Getenv(EmulatorHostEnv) == "" {
+		return nil, fmt.Errorf("NewEmulatorClient: %s is not set", EmulatorHostEnv)
+	}
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID})
+	if err != nil {
+		return nil, fmt.Errorf("NewEmulatorClient: firebase.NewApp: %w", err)
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("NewEmulatorClient: app.Auth: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cprime50/fire-go/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testKid = "test-key"
+
+func newTestLocalAuth(t *testing.T) (*LocalAuth, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": testKid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewLocalAuth(path, "fire-go-test", "fire-go")
+	if err != nil {
+		t.Fatalf("NewLocalAuth: %v", err)
+	}
+	return local, key
+}
+
+func mintToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func TestLocalAuthVerifyIDToken(t *testing.T) {
+	local, key := newTestLocalAuth(t)
+	now := time.Now()
+
+	// Test case 1: a valid token
+	token, err := local.VerifyIDToken(context.Background(), mintToken(t, key, jwt.MapClaims{
+		"iss":   "fire-go-test",
+		"aud":   "fire-go",
+		"iat":   now.Unix(),
+		"sub":   "uid1",
+		"email": "test1@email.com",
+		"exp":   now.Add(time.Hour).Unix(),
+	}))
+	if err != nil {
+		t.Fatalf("VerifyIDToken error: %v", err)
+	}
+	if token.UID != "uid1" || token.Claims["email"] != "test1@email.com" {
+		t.Errorf("VerifyIDToken error: unexpected token %+v", token)
+	}
+
+	// Test case 2: an expired token
+	_, err = local.VerifyIDToken(context.Background(), mintToken(t, key, jwt.MapClaims{
+		"iss": "fire-go-test",
+		"aud": "fire-go",
+		"iat": now.Unix(),
+		"sub": "uid1",
+		"exp": now.Add(-time.Hour).Unix(),
+	}))
+	if err == nil {
+		t.Error("VerifyIDToken error: expired token accepted")
+	}
+
+	// Test case 3: the wrong issuer
+	_, err = local.VerifyIDToken(context.Background(), mintToken(t, key, jwt.MapClaims{
+		"iss": "someone-else",
+		"aud": "fire-go",
+		"iat": now.Unix(),
+		"sub": "uid1",
+		"exp": now.Add(time.Hour).Unix(),
+	}))
+	if err == nil {
+		t.Error("VerifyIDToken error: wrong issuer accepted")
+	}
+
+	// Test case 4: a token without an expiry
+	_, err = local.VerifyIDToken(context.Background(), mintToken(t, key, jwt.MapClaims{
+		"iss": "fire-go-test",
+		"aud": "fire-go",
+		"iat": now.Unix(),
+		"sub": "uid1",
+	}))
+	if err == nil {
+		t.Error("VerifyIDToken error: token without exp accepted")
+	}
+
+	// Test case 5: a token without an issue time
+	_, err = local.VerifyIDToken(context.Background(), mintToken(t, key, jwt.MapClaims{
+		"iss": "fire-go-test",
+		"aud": "fire-go",
+		"sub": "uid1",
+		"exp": now.Add(time.Hour).Unix(),
+	}))
+	if err == nil {
+		t.Error("VerifyIDToken error: token without iat accepted")
+	}
+
+	// Test case 6: a token for another audience
+	_, err = local.VerifyIDToken(context.Background(), mintToken(t, key, jwt.MapClaims{
+		"iss": "fire-go-test",
+		"aud": "another-project",
+		"iat": now.Unix(),
+		"sub": "uid1",
+		"exp": now.Add(time.Hour).Unix(),
+	}))
+	if err == nil {
+		t.Error("VerifyIDToken error: wrong audience accepted")
+	}
+}
+
+func TestAuthWithLocalVerifier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	local, key := newTestLocalAuth(t)
+
+	r := gin.New()
+	r.GET("/me", Auth(local, policy.Default()), func(c *gin.Context) {
+		user, _ := c.Get("user")
+		c.JSON(http.StatusOK, user)
+	})
+
+	idToken := mintToken(t, key, jwt.MapClaims{
+		"iss":   "fire-go-test",
+		"aud":   "fire-go",
+		"iat":   time.Now().Unix(),
+		"sub":   "uid1",
+		"email": "test1@email.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	// Test case 1: first request assigns the default role
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+idToken)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Auth error: expected 200, got %d", w.Code)
+	}
+	var user User
+	_ = json.Unmarshal(w.Body.Bytes(), &user)
+	if user.UserID != "uid1" || user.Role != "user" {
+		t.Errorf("Auth error: unexpected user %+v", user)
+	}
+
+	// Test case 2: a role assigned afterwards shows up on the same token
+	if err := AssignRole(context.Background(), local, "test1@email.com", "admin"); err != nil {
+		t.Fatalf("AssignRole error: %v", err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), &user)
+	if user.Role != "admin" {
+		t.Errorf("Auth error: expected admin role, got %s", user.Role)
+	}
+
+	// Test case 3: switching roles drops the old role flag
+	if err := AssignRole(context.Background(), local, "test1@email.com", policy.RoleModerator); err != nil {
+		t.Fatalf("AssignRole error: %v", err)
+	}
+	record, _ := local.GetUserByEmail(context.Background(), "test1@email.com")
+	if record.CustomClaims["admin"] != nil || record.CustomClaims["moderator"] != true {
+		t.Errorf("AssignRole error: unexpected claims %v", record.CustomClaims)
+	}
+
+	// Test case 4: missing token
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Auth error: expected 401, got %d", w.Code)
+	}
+}
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	local, key := newTestLocalAuth(t)
+
+	r := gin.New()
+	r.GET("/quotes", OptionalAuth(local, policy.Default()), func(c *gin.Context) {
+		a, ok := CurrentActor(c)
+		c.JSON(http.StatusOK, gin.H{"signed_in": ok, "uid": a.UID})
+	})
+	get := func(header string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		r.ServeHTTP(w, req)
+		var body map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	// Test case 1: a valid token signs the caller in
+	idToken := mintToken(t, key, jwt.MapClaims{
+		"iss":   "fire-go-test",
+		"aud":   "fire-go",
+		"iat":   time.Now().Unix(),
+		"sub":   "uid1",
+		"email": "test1@email.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	if code, body := get("Bearer " + idToken); code != http.StatusOK || body["signed_in"] != true || body["uid"] != "uid1" {
+		t.Errorf("OptionalAuth error: expected uid1 signed in, got %d %v", code, body)
+	}
+
+	// Test case 2: no token, a malformed header or a bad token continue anonymously
+	for _, header := range []string{"", "Token abc", "Bearer not-a-jwt"} {
+		if code, body := get(header); code != http.StatusOK || body["signed_in"] != false {
+			t.Errorf("OptionalAuth(%q) error: expected anonymous, got %d %v", header, code, body)
+		}
+	}
+
+	// Test case 3: a verified token without an email claim continues anonymously
+	noEmail := mintToken(t, key, jwt.MapClaims{
+		"iss": "fire-go-test",
+		"aud": "fire-go",
+		"iat": time.Now().Unix(),
+		"sub": "uid1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if code, body := get("Bearer " + noEmail); code != http.StatusOK || body["signed_in"] != false {
+		t.Errorf("OptionalAuth error: expected anonymous without an email claim, got %d %v", code, body)
+	}
+}
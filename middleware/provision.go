@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/gin-gonic/gin"
+)
+
+// Provisioner sets up whatever a signed-in user needs, such as their profile,
+// before their requests are handled. It is called on every authenticated
+// request, so it should remember who it has already provisioned.
+type Provisioner interface {
+	Provision(ctx context.Context, a actor.Actor) error
+}
+
+// ConflictError is returned by a Provisioner when the caller's account
+// clashes with another one, such as an email that already has a profile.
+// Only the caller can resolve it, so Provision reports it instead of carrying on.
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string { return e.Err.Error() }
+
+func (e *ConflictError) Unwrap() error { return e.Err }
+
+// Provision runs p for the caller stored by Auth or OptionalAuth, and does
+// nothing for anonymous callers or when p is nil. A *ConflictError aborts the
+// request with 409 Conflict. Any other failure is logged and attached to the
+// request with ctx.Error, and the request carries on so one user's bad state
+// never locks them out; the next request tries again.
+func Provision(p Provisioner) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		a, ok := CurrentActor(ctx)
+		if p == nil || !ok {
+			ctx.Next()
+			return
+		}
+		if err := p.Provision(ctx.Request.Context(), a); err != nil {
+			log.Printf("Error provisioning user %s: %v\n", a.UID, err)
+			var conflict *ConflictError
+			if errors.As(err, &conflict) {
+				ctx.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			_ = ctx.Error(err)
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/gin-gonic/gin"
+)
+
+// provisionerFunc adapts a function to the Provisioner interface.
+type provisionerFunc func(ctx context.Context, a actor.Actor) error
+
+func (f provisionerFunc) Provision(ctx context.Context, a actor.Actor) error {
+	return f(ctx, a)
+}
+
+func TestProvision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var result error
+	p := provisionerFunc(func(ctx context.Context, a actor.Actor) error { return result })
+
+	r := gin.New()
+	r.GET("/me", func(c *gin.Context) {
+		c.Set("user", &User{UserID: "uid1", Email: "test1@email.com"})
+	}, Provision(p), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"errors": len(c.Errors)})
+	})
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+		return w
+	}
+
+	// Test case 1: other failures are attached to the request, which carries on
+	result = errors.New("database is down")
+	if w := get(); w.Code != http.StatusOK || w.Body.String() != `{"errors":1}` {
+		t.Errorf("Provision error: expected the request to carry on, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Test case 2: a conflict is reported to the caller
+	result = &ConflictError{Err: errors.New("email is already used by another profile")}
+	if w := get(); w.Code != http.StatusConflict || w.Body.String() != `{"error":"email is already used by another profile"}` {
+		t.Errorf("Provision error: expected 409, got %d %s", w.Code, w.Body.String())
+	}
+}
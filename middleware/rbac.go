@@ -6,45 +6,54 @@ import (
 	"log"
 	"net/http"
 
-	"firebase.google.com/go/v4/auth"
+	"github.com/cprime50/fire-go/policy"
 	"github.com/gin-gonic/gin"
 )
 
-func RoleAuth(requiredRole string) gin.HandlerFunc {
+// RequirePermission aborts with 403 unless the authenticated actor holds every
+// one of permissions with the :any scope. Holding a permission only for
+// :own is not enough; routes that allow it leave the ownership check to the
+// service instead of using RequirePermission.
+func RequirePermission(permissions ...policy.Permission) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		userValue, exists := ctx.Get("user")
-		if !exists {
+		a, ok := CurrentActor(ctx)
+		if !ok {
 			log.Println("User not found in context")
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		user, ok := userValue.(*User)
-		if !ok || user == nil {
-			log.Println("Invalid user data in context")
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
+		for _, permission := range permissions {
+			if !policy.Can(a, permission, policy.Any) {
+				log.Printf("User with email %s and roles %v tried to access a route that requires the %s permission",
+					a.Email, a.Roles, permission)
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+				return
+			}
 		}
 
-		if user.Role == "" {
-			log.Println("User role not set")
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
+		log.Printf("User with email %s and roles %v authorized", a.Email, a.Roles)
+		ctx.Next()
+	}
+}
 
-		if user.Role != requiredRole {
-			log.Printf("User with email %s and role %s tried to access a route that was for the %s role only",
-				user.Email, user.Role, requiredRole)
-			ctx.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
+// builtinRoles each get a boolean claim of the same name next to the "role" claim.
+var builtinRoles = []string{policy.RoleUser, policy.RoleModerator, policy.RoleAdmin}
 
-		log.Printf("User with email %s and role %s authorized", user.Email, user.Role)
-		ctx.Next()
+// GetRole returns the role stored in the custom claims of the user with email.
+func GetRole(ctx context.Context, client ClaimsStore, email string) (string, error) {
+	user, err := client.GetUserByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", fmt.Errorf("GetRole Error: User with email %s not found", email)
 	}
+	role, _ := user.CustomClaims["role"].(string)
+	return role, nil
 }
 
-func AssignRole(ctx context.Context, client *auth.Client, email string, role string) error {
+func AssignRole(ctx context.Context, client ClaimsStore, email string, role string) error {
 	user, err := client.GetUserByEmail(ctx, email)
 	if err != nil {
 		return err
@@ -57,12 +66,10 @@ func AssignRole(ctx context.Context, client *auth.Client, email string, role str
 		currentCustomClaims = map[string]interface{}{}
 	}
 	currentCustomClaims["role"] = role
-	if role == "admin" {
-		currentCustomClaims["admin"] = true
-	}
-	if role == "user" {
-		currentCustomClaims["user"] = true
+	for _, r := range builtinRoles {
+		delete(currentCustomClaims, r)
 	}
+	currentCustomClaims[role] = true
 	if err := client.SetCustomUserClaims(ctx, user.UID, currentCustomClaims); err != nil {
 		return fmt.Errorf("AssignRole Error: Error setting custom claims: %w", err)
 	}
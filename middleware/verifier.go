@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// TokenVerifier checks an ID token and returns its decoded claims.
+type TokenVerifier interface {
+	VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error)
+}
+
+// ClaimsStore looks up users and stores the custom claims that carry their role.
+type ClaimsStore interface {
+	GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error)
+	SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error
+}
+
+// AuthClient is everything the auth middleware needs from an identity provider.
+// *auth.Client satisfies it, and so does LocalAuth for running without Firebase.
+type AuthClient interface {
+	TokenVerifier
+	ClaimsStore
+}
+
+var (
+	_ AuthClient = (*auth.Client)(nil)
+	_ AuthClient = (*LocalAuth)(nil)
+)
@@ -0,0 +1,132 @@
+// Package page implements cursor pagination for listings ordered by
+// (created_at, id).
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Sort orders.
+const (
+	Newest = "newest"
+	Oldest = "oldest"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// Cursor points at the last row of the previous page. It is handed to
+// clients as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Sort      string    `json:"s"`
+}
+
+// Encode returns the opaque form of c.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Params selects one page of a listing.
+type Params struct {
+	Limit int
+	Sort  string
+	// After is nil for the first page.
+	After *Cursor
+}
+
+// Parse builds Params from the raw limit, cursor and sort query values. Empty
+// values fall back to DefaultLimit, the first page and Newest. A cursor must
+// be used with the sort it was issued for. extra lists sorts besides Newest
+// and Oldest that the caller orders by itself.
+func Parse(limit, cursor, sort string, extra ...string) (Params, error) {
+	p := Params{Limit: DefaultLimit, Sort: Newest}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Params{}, ErrInvalidLimit
+		}
+		p.Limit = n
+	}
+	if sort != "" {
+		if sort != Newest && sort != Oldest && !slices.Contains(extra, sort) {
+			return Params{}, ErrInvalidSort
+		}
+		p.Sort = sort
+	}
+	if cursor != "" {
+		c, err := decode(cursor)
+		if err != nil {
+			return Params{}, err
+		}
+		if c.Sort != p.Sort {
+			return Params{}, ErrInvalidCursor
+		}
+		p.After = c
+	}
+	return p, nil
+}
+
+// Where returns the condition selecting rows after p.After, and ORDER BY
+// returns the matching ordering. Both expect created_at and id columns; args
+// are numbered from n.
+func (p Params) Where(n int) (string, []any) {
+	if p.After == nil {
+		return "1 = 1", nil
+	}
+	op := "<"
+	if p.Sort == Oldest {
+		op = ">"
+	}
+	a, b := "$"+strconv.Itoa(n), "$"+strconv.Itoa(n+1)
+	return "(created_at " + op + " " + a + " OR (created_at = " + a + " AND id " + op + " " + b + "))",
+		[]any{p.After.CreatedAt, p.After.ID}
+}
+
+// OrderBy returns the ORDER BY and LIMIT clauses. One extra row is fetched
+// so Trim can tell whether there is another page.
+func (p Params) OrderBy() string {
+	dir := "DESC"
+	if p.Sort == Oldest {
+		dir = "ASC"
+	}
+	return "ORDER BY created_at " + dir + ", id " + dir + " LIMIT " + strconv.Itoa(p.Limit+1)
+}
+
+// Trim cuts items fetched with OrderBy down to the page and returns the
+// cursor for the next one, or "" on the last page.
+func Trim[T any](items []T, p Params, cursorOf func(T) (time.Time, string)) ([]T, string) {
+	if len(items) <= p.Limit {
+		return items, ""
+	}
+	items = items[:p.Limit]
+	createdAt, id := cursorOf(items[len(items)-1])
+	return items, Cursor{CreatedAt: createdAt, ID: id, Sort: p.Sort}.Encode()
+}
@@ -0,0 +1,45 @@
+package page
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	next := Cursor{CreatedAt: time.Now(), ID: "id1", Sort: Newest}.Encode()
+
+	testCases := []struct {
+		name                string
+		limit, cursor, sort string
+		extra               []string
+		wantErr             error
+		wantLimit           int
+		wantSort            string
+		wantAfter           bool
+	}{
+		{name: "defaults", wantLimit: DefaultLimit, wantSort: Newest},
+		{name: "limit and sort", limit: "5", sort: Oldest, wantLimit: 5, wantSort: Oldest},
+		{name: "cursor", cursor: next, wantLimit: DefaultLimit, wantSort: Newest, wantAfter: true},
+		{name: "limit too large", limit: "1000", wantErr: ErrInvalidLimit},
+		{name: "limit not a number", limit: "ten", wantErr: ErrInvalidLimit},
+		{name: "unknown sort", sort: "random", wantErr: ErrInvalidSort},
+		{name: "extra sort", sort: "risk", extra: []string{"risk"}, wantLimit: DefaultLimit, wantSort: "risk"},
+		{name: "garbage cursor", cursor: "not-a-cursor", wantErr: ErrInvalidCursor},
+		{name: "cursor for another sort", cursor: next, sort: Oldest, wantErr: ErrInvalidCursor},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := Parse(tc.limit, tc.cursor, tc.sort, tc.extra...)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Parse error: expected %v, got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if p.Limit != tc.wantLimit || p.Sort != tc.wantSort || (p.After != nil) != tc.wantAfter {
+				t.Errorf("Parse error: unexpected params %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package policy maps roles to named permissions and answers whether an actor
+// may perform an action on a resource. Roles and their permissions come from
+// roles.json, which can be replaced at startup with RBAC_POLICY_FILE.
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/cprime50/fire-go/actor"
+)
+
+// Built-in roles. A policy file may define more.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission is an action such as "quote:update", optionally narrowed by a
+// scope: "quote:update:own" covers the actor's own resources and
+// "quote:update:any" covers everyone's.
+type Permission string
+
+const (
+	QuoteCreate   Permission = "quote:create"
+	QuoteRead     Permission = "quote:read"
+	QuoteUpdate   Permission = "quote:update"
+	QuoteDelete   Permission = "quote:delete"
+	QuoteApprove  Permission = "quote:approve"
+	QuoteReport   Permission = "quote:report"
+	QuoteLike     Permission = "quote:like"
+	ProfileRead   Permission = "profile:read"
+	ProfileDelete Permission = "profile:delete"
+	RoleManage    Permission = "role:manage"
+	TagManage     Permission = "tag:manage"
+	AuthorManage  Permission = "author:manage"
+	SpamManage    Permission = "spam:manage"
+	ReportManage  Permission = "report:manage"
+	CommentCreate Permission = "comment:create"
+	CommentUpdate Permission = "comment:update"
+	CommentDelete Permission = "comment:delete"
+	CommentLock   Permission = "comment:lock"
+)
+
+// actions lists every action a policy file may grant.
+var actions = []Permission{
+	QuoteCreate,
+	QuoteRead,
+	QuoteUpdate,
+	QuoteDelete,
+	QuoteApprove,
+	QuoteReport,
+	QuoteLike,
+	ProfileRead,
+	ProfileDelete,
+	RoleManage,
+	TagManage,
+	AuthorManage,
+	SpamManage,
+	ReportManage,
+	CommentCreate,
+	CommentUpdate,
+	CommentDelete,
+	CommentLock,
+}
+
+func (p Permission) Own() Permission { return p + ":own" }
+func (p Permission) Any() Permission { return p + ":any" }
+
+// Resource is what an action is performed on. OwnerID is empty for actions
+// that do not target a single user's resource, such as listing everything.
+type Resource struct {
+	OwnerID string
+}
+
+// Any is a resource that belongs to nobody in particular.
+var Any = Resource{}
+
+// OwnedBy returns a resource belonging to the user with the given ID.
+func OwnedBy(ownerID string) Resource {
+	return Resource{OwnerID: ownerID}
+}
+
+// Can reports whether a holds action itself, action:any, or action:own on a
+// resource it owns.
+func Can(a actor.Actor, action Permission, resource Resource) bool {
+	if a.HasPermission(string(action)) || a.HasPermission(string(action.Any())) {
+		return true
+	}
+	return resource.OwnerID != "" && resource.OwnerID == a.UID && a.HasPermission(string(action.Own()))
+}
+
+//go:embed roles.json
+var defaultRoles []byte
+
+type Policy struct {
+	roles map[string][]Permission
+}
+
+type policyFile struct {
+	Roles map[string][]Permission `json:"roles"`
+}
+
+// Default returns the policy in the embedded roles.json.
+func Default() *Policy {
+	p, err := parse(defaultRoles)
+	if err != nil {
+		panic(fmt.Sprintf("policy: embedded roles.json is invalid: %v", err))
+	}
+	return p
+}
+
+// Load reads a policy from path, or returns Default when path is empty.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy.Load: %w", err)
+	}
+	p, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("policy.Load %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func parse(raw []byte) (*Policy, error) {
+	var file policyFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, err
+	}
+	known := map[Permission]bool{}
+	for _, action := range actions {
+		known[action] = true
+		known[action.Own()] = true
+		known[action.Any()] = true
+	}
+	for role, permissions := range file.Roles {
+		for _, permission := range permissions {
+			if !known[permission] {
+				return nil, fmt.Errorf("role %s: unknown permission %q", role, permission)
+			}
+		}
+	}
+	return &Policy{roles: file.Roles}, nil
+}
+
+// PermissionsFor returns the permissions granted by roles, without duplicates.
+func (p *Policy) PermissionsFor(roles ...string) []string {
+	set := map[string]bool{}
+	for _, role := range roles {
+		for _, permission := range p.roles[role] {
+			set[string(permission)] = true
+		}
+	}
+	permissions := make([]string, 0, len(set))
+	for permission := range set {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// HasRole reports whether role is defined by the policy.
+func (p *Policy) HasRole(role string) bool {
+	_, ok := p.roles[role]
+	return ok
+}
@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cprime50/fire-go/actor"
+)
+
+func TestCan(t *testing.T) {
+	p := Default()
+	user := actor.Actor{UID: "user1", Roles: []string{"user"}, Permissions: p.PermissionsFor("user")}
+	admin := actor.Actor{UID: "admin1", Roles: []string{"admin"}, Permissions: p.PermissionsFor("admin")}
+
+	cases := []struct {
+		name     string
+		actor    actor.Actor
+		action   Permission
+		resource Resource
+		want     bool
+	}{
+		{"user updates own quote", user, QuoteUpdate, OwnedBy("user1"), true},
+		{"user updates someone else's quote", user, QuoteUpdate, OwnedBy("user2"), false},
+		{"user approves", user, QuoteApprove, Any, false},
+		{"admin updates any quote", admin, QuoteUpdate, OwnedBy("user2"), true},
+		{"admin approves", admin, QuoteApprove, Any, true},
+		{"own scope does not cover unowned resources", user, QuoteRead, Any, false},
+		{"anonymous", actor.Actor{}, QuoteCreate, OwnedBy(""), false},
+	}
+	for _, c := range cases {
+		if got := Can(c.actor, c.action, c.resource); got != c.want {
+			t.Errorf("Can error: %s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	// Test case 1: a valid policy file
+	path := filepath.Join(dir, "roles.json")
+	_ = os.WriteFile(path, []byte(`{"roles": {"editor": ["quote:update:any"]}}`), 0o600)
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if !p.HasRole("editor") || p.HasRole("admin") {
+		t.Errorf("Load error: unexpected roles")
+	}
+
+	// Test case 2: a typo in a permission is rejected
+	_ = os.WriteFile(path, []byte(`{"roles": {"editor": ["quote:updte:any"]}}`), 0o600)
+	if _, err := Load(path); err == nil {
+		t.Error("Load error: unknown permission accepted")
+	}
+
+	// Test case 3: no path falls back to the embedded policy
+	p, err = Load("")
+	if err != nil || !p.HasRole("admin") {
+		t.Errorf("Load error: default policy not loaded: %v", err)
+	}
+}
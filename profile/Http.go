@@ -3,21 +3,27 @@ package profile
 import (
 	"errors"
 	"net/http"
+	"net/url"
 
+	"github.com/cprime50/fire-go/actor"
 	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/quote"
 	"github.com/gin-gonic/gin"
 )
 
-func CreateProfileHandler(c *gin.Context, s ProfileServiceImpl) {
-	user, ok := getUserFromCtx(c)
+func CreateProfileHandler(c *gin.Context, s ProfileService) {
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	response, err := s.CreateProfile(user.UserID, user.Email)
+	response, err := s.CreateProfile(c.Request.Context(), a)
 	if err != nil {
 		if errors.Is(err, ErrProfileAlreadyExists) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		} else if errors.Is(err, ErrEmailInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		}
@@ -27,21 +33,29 @@ func CreateProfileHandler(c *gin.Context, s ProfileServiceImpl) {
 	c.JSON(http.StatusCreated, gin.H{"message": response.Message, "profile": response.Profile})
 }
 
-func UpdateProfileHandler(c *gin.Context, s ProfileServiceImpl) {
-	user, ok := getUserFromCtx(c)
+func UpdateProfileHandler(c *gin.Context, s ProfileService) {
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	if err := c.ShouldBindJSON(&UpdateProfileReq); err != nil {
+	var req struct {
+		Bio      string `json:"bio"`
+		Username string `json:"username"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err})
 		return
 	}
-	response, err := s.UpdateProfile(user.UserID, UpdateProfileReq.Bio, UpdateProfileReq.Username)
+	response, err := s.UpdateProfile(c.Request.Context(), a, req.Bio, req.Username)
 	if err != nil {
 		if errors.Is(err, ErrProfileNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err})
+		} else if errors.Is(err, ErrInvalidUsername) || errors.Is(err, ErrReservedUsername) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		}
@@ -50,19 +64,48 @@ func UpdateProfileHandler(c *gin.Context, s ProfileServiceImpl) {
 	c.JSON(http.StatusOK, gin.H{"message": response.Message, "profile": response.Profile})
 }
 
-func DeleteProfileHandler(c *gin.Context, service ProfileServiceImpl) {
+// UsernameAvailableHandler answers whether the username in ?u= can be taken,
+// with the reason when it cannot.
+func UsernameAvailableHandler(c *gin.Context, s ProfileService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	username := c.Query("u")
+	available, err := s.UsernameAvailable(c.Request.Context(), a, username)
+	if err != nil {
+		if errors.Is(err, ErrInvalidUsername) || errors.Is(err, ErrReservedUsername) {
+			c.JSON(http.StatusOK, gin.H{"username": username, "available": false, "reason": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	response := gin.H{"username": username, "available": available}
+	if !available {
+		response["reason"] = ErrUsernameTaken.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func DeleteProfileHandler(c *gin.Context, service ProfileService) {
 	profileId := c.Param("id")
 
-	user, ok := getUserFromCtx(c)
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	err := service.DeleteProfile(profileId, user.Role, user.UserID)
+	err := service.DeleteProfile(c.Request.Context(), a, profileId)
 	if err != nil {
 		if errors.Is(err, ErrProfileNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err})
+		} else if errors.Is(err, ErrNotAuthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		}
@@ -72,10 +115,15 @@ func DeleteProfileHandler(c *gin.Context, service ProfileServiceImpl) {
 	c.JSON(http.StatusOK, gin.H{"message": "Profile deleted successfully"})
 }
 
-func GetProfileHandler(c *gin.Context, service ProfileServiceImpl) {
+// GetProfileHandler returns the profile of the user with the given ID. It is
+// served with optional authentication; emails are only shown to their owner
+// and to those who can read any profile.
+func GetProfileHandler(c *gin.Context, service ProfileService) {
 	userID := c.Param("id")
 
-	profile, err := service.GetProfile(userID)
+	a, _ := getActorFromCtx(c)
+
+	profile, err := service.GetProfile(c.Request.Context(), a, userID)
 	if err != nil {
 		if errors.Is(err, ErrProfileNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err})
@@ -88,24 +136,69 @@ func GetProfileHandler(c *gin.Context, service ProfileServiceImpl) {
 	c.JSON(http.StatusOK, gin.H{"profile": profile})
 }
 
-func GetAllProfilesHandler(c *gin.Context, service ProfileServiceImpl) {
-	profiles, err := service.GetAllProfiles()
+// GetPublicProfileHandler returns the public profile with the given username
+// and one page of that user's approved quotes, taking limit, cursor and sort.
+// It needs no sign-in. Old usernames redirect to the current one.
+func GetPublicProfileHandler(c *gin.Context, s ProfileService, quotes quote.QuoteService) {
+	p, err := page.Parse(c.Query("limit"), c.Query("cursor"), c.Query("sort"))
 	if err != nil {
-		if errors.Is(err, ErrProfileNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := s.GetPublicProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		var changed *UsernameChangedError
+		if errors.As(err, &changed) {
+			location := "/u/" + url.PathEscape(changed.Username)
+			if c.Request.URL.RawQuery != "" {
+				location += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusFound, location)
+		} else if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+	published, next, err := quotes.GetPublishedQuotesByUserId(c.Request.Context(), profile.UserId, p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"profile": profile, "quotes": published, "next_cursor": next})
 }
 
-func getUserFromCtx(ctx *gin.Context) (*middleware.User, bool) {
-	user, exists := ctx.Get("user")
-	if !exists {
-		return nil, false
+func GetAllProfilesHandler(c *gin.Context, service ProfileService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	p, err := page.Parse(c.Query("limit"), c.Query("cursor"), c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	return user.(*middleware.User), true
+
+	profiles, next, err := service.GetAllProfiles(c.Request.Context(), a, p)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if errors.Is(err, ErrNotAuthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"profiles": profiles, "next_cursor": next})
+}
+
+func getActorFromCtx(ctx *gin.Context) (actor.Actor, bool) {
+	return middleware.CurrentActor(ctx)
 }
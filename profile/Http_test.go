@@ -0,0 +1,55 @@
+package profile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/policy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateProfileHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repo := newFakeProfileRepository()
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test1", Email: "test1@email.com", UserName: "alice"})
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test2", Email: "test2@email.com", UserName: "bob"})
+	s := NewProfileService(repo)
+
+	r := gin.New()
+	r.PUT("/profile/update", func(c *gin.Context) {
+		c.Set("user", &middleware.User{
+			UserID:      c.GetHeader("X-Test-User"),
+			Role:        policy.RoleUser,
+			Permissions: policy.Default().PermissionsFor(policy.RoleUser),
+		})
+		UpdateProfileHandler(c, s)
+	})
+	update := func(userId, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/profile/update", strings.NewReader(body))
+		req.Header.Set("X-Test-User", userId)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Test case 1: one user renames themselves
+	if code := update("test1", `{"bio": "hi", "username": "alice2"}`); code != http.StatusOK {
+		t.Fatalf("UpdateProfileHandler error: expected 200, got %d", code)
+	}
+
+	// Test case 2: the next request without a username keeps its own, not the previous request's
+	if code := update("test2", `{"bio": "x"}`); code != http.StatusOK {
+		t.Fatalf("UpdateProfileHandler error: expected 200, got %d", code)
+	}
+	if p, _ := repo.GetProfileByUserId(ctx, "test2"); p.UserName != "bob" || p.Bio != "x" {
+		t.Errorf("UpdateProfileHandler error: expected bob with the new bio, got %+v", p)
+	}
+	if p, _ := repo.GetProfileByUserId(ctx, "test1"); p.UserName != "alice2" {
+		t.Errorf("UpdateProfileHandler error: expected alice2, got %+v", p)
+	}
+}
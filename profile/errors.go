@@ -1,6 +1,9 @@
 package profile
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrQuoteNotFound             = errors.New("quote not found")
@@ -14,4 +17,24 @@ var (
 	ErrProfileAlreadyExists      = errors.New("profile already exists")
 	ErrForeignKeyViolation       = errors.New("foreign key violation")
 	ErrUniqueConstraintViolation = errors.New("unique constraint violation")
+	ErrInvalidUsername           = errors.New("username must be 3 to 30 letters, digits, underscores or hyphens, starting with a letter or digit")
+	ErrReservedUsername          = errors.New("username is reserved")
+	ErrUsernameTaken             = errors.New("username is already taken")
+	ErrUsernameChanged           = errors.New("username has changed")
+	ErrEmailInUse                = errors.New("email is already used by another profile")
+	ErrSyncEmail                 = errors.New("failed to update profile email")
 )
+
+// UsernameChangedError is returned when a profile is looked up by a username
+// its owner has since changed. It matches ErrUsernameChanged with errors.Is.
+type UsernameChangedError struct {
+	Username string
+}
+
+func (e *UsernameChangedError) Error() string {
+	return fmt.Sprintf("%v to %s", ErrUsernameChanged, e.Username)
+}
+
+func (e *UsernameChangedError) Is(target error) bool {
+	return target == ErrUsernameChanged
+}
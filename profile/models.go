@@ -12,12 +12,24 @@ type Profile struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PublicProfile is the part of a profile anyone may see.
+type PublicProfile struct {
+	UserId    string    `json:"user_id"`
+	UserName  string    `json:"username"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p *Profile) Public() *PublicProfile {
+	return &PublicProfile{
+		UserId:    p.UserId,
+		UserName:  p.UserName,
+		Bio:       p.Bio,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
 type ProfileResponse struct {
 	Profile *Profile
 	Message string
 }
-
-var UpdateProfileReq struct {
-	Bio      string `json:"bio"`
-	Username string `json:"username"`
-}
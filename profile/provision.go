@@ -0,0 +1,87 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/middleware"
+)
+
+// Provisioner is a ProfileService that creates a user's profile the first
+// time they make an authenticated request, and keeps its email in step with
+// their token. It remembers the email each user's profile has, so only
+// requests after a sign-up or an email change touch the database; one
+// instance should be shared by every route. It satisfies
+// middleware.Provisioner.
+type Provisioner struct {
+	ProfileService
+	// known maps UIDs to the email their profile has. It grows by one small
+	// entry per user and is only emptied by a restart.
+	known sync.Map
+	// deleted holds the UIDs whose profile was deleted on purpose, so they
+	// are not looked up again on every request.
+	deleted sync.Map
+}
+
+func NewProvisioner(service ProfileService) *Provisioner {
+	return &Provisioner{ProfileService: service}
+}
+
+// Provision creates a's profile, or updates its email to a's, unless it is
+// known to be up to date. Users who deleted their profile are left alone
+// until they create one with CreateProfile. If a's email belongs to another profile it returns
+// ErrEmailInUse wrapped in a *middleware.ConflictError, and tries again on
+// a's next request.
+func (p *Provisioner) Provision(ctx context.Context, a actor.Actor) error {
+	if a.UID == "" {
+		return nil
+	}
+	if email, ok := p.known.Load(a.UID); ok && email == a.Email {
+		return nil
+	}
+	if _, ok := p.deleted.Load(a.UID); ok {
+		return nil
+	}
+	deleted, err := p.WasDeleted(ctx, a.UID)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		p.deleted.Store(a.UID, true)
+		return nil
+	}
+	_, err = p.CreateProfile(ctx, a)
+	if errors.Is(err, ErrProfileAlreadyExists) {
+		err = p.SyncEmail(ctx, a)
+	}
+	if errors.Is(err, ErrEmailInUse) {
+		return &middleware.ConflictError{Err: err}
+	}
+	if err != nil {
+		return err
+	}
+	p.known.Store(a.UID, a.Email)
+	return nil
+}
+
+func (p *Provisioner) CreateProfile(ctx context.Context, a actor.Actor) (*ProfileResponse, error) {
+	response, err := p.ProfileService.CreateProfile(ctx, a)
+	if err == nil {
+		p.deleted.Delete(a.UID)
+		p.known.Store(a.UID, a.Email)
+	}
+	return response, err
+}
+
+// DeleteProfile also forgets the user and stops provisioning them, so the
+// profile stays deleted until they create it again.
+func (p *Provisioner) DeleteProfile(ctx context.Context, a actor.Actor, userID string) error {
+	err := p.ProfileService.DeleteProfile(ctx, a, userID)
+	if err == nil {
+		p.known.Delete(userID)
+		p.deleted.Store(userID, true)
+	}
+	return err
+}
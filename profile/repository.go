@@ -1,20 +1,54 @@
 package profile
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/cprime50/fire-go/db"
+	"github.com/cprime50/fire-go/page"
 	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
 )
 
-func createProfile(p *Profile) error {
+type ProfileRepository interface {
+	CreateProfile(ctx context.Context, p *Profile) error
+	GetProfileByUserId(ctx context.Context, userId string) (*Profile, error)
+	GetProfileByUsername(ctx context.Context, username string) (*Profile, error)
+	GetUsernamesWithPrefix(ctx context.Context, prefix string) ([]string, error)
+	GetUsernameRedirect(ctx context.Context, username string) (string, error)
+	UpdateEmail(ctx context.Context, userId, email string) error
+	UpdateProfile(ctx context.Context, p *Profile) error
+	DeleteProfile(ctx context.Context, userId string) error
+	WasDeleted(ctx context.Context, userId string) (bool, error)
+	GetAllProfiles(ctx context.Context, p page.Params) ([]*Profile, error)
+}
+
+// SQLiteProfileRepository stores profiles in the profiles table.
+type SQLiteProfileRepository struct {
+	db *sql.DB
+}
+
+func NewProfileRepository(db *sql.DB) *SQLiteProfileRepository {
+	return &SQLiteProfileRepository{db: db}
+}
+
+// CreateProfile inserts p and clears any record of the user having deleted an
+// earlier profile.
+func (r *SQLiteProfileRepository) CreateProfile(ctx context.Context, p *Profile) error {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return fmt.Errorf("uuid.NewRandom: %w", err)
 	}
-	_, err = db.Db.Exec(
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("CreateProfile BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
 		"INSERT INTO profiles (id, user_id, email, username, bio, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
 		id.String(),
 		p.UserId,
@@ -24,16 +58,30 @@ func createProfile(p *Profile) error {
 		time.Now(),
 	)
 	if err != nil {
+		if isUsernameTaken(err) {
+			return ErrUsernameTaken
+		}
+		if isEmailInUse(err) {
+			return ErrEmailInUse
+		}
 		return fmt.Errorf("CreateProfile error: %w", err)
 	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM deleted_profiles WHERE user_id = $1", p.UserId)
+	if err != nil {
+		return fmt.Errorf("CreateProfile deleted_profiles: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("CreateProfile Commit: %w", err)
+	}
 	return nil
 }
 
 // GetProfileByUserId retrieves a user profile by user ID.
-func getProfileByUserId(userId string) (*Profile, error) {
+func (r *SQLiteProfileRepository) GetProfileByUserId(ctx context.Context, userId string) (*Profile, error) {
 	profile := &Profile{}
 	var createdAt, updatedAt time.Time
-	err := db.Db.QueryRow("SELECT id, user_id, email, username, bio, created_at, updated_at FROM profiles WHERE user_id = $1", userId).
+	err := r.db.QueryRowContext(ctx, "SELECT id, user_id, email, username, bio, created_at, updated_at FROM profiles WHERE user_id = $1", userId).
 		Scan(&profile.Id, &profile.UserId, &profile.Email, &profile.UserName, &profile.Bio, &createdAt, &updatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -46,34 +94,213 @@ func getProfileByUserId(userId string) (*Profile, error) {
 	return profile, nil
 }
 
-func updateProfile(p *Profile) error {
-	result, err := db.Db.Exec(
+// GetProfileByUsername retrieves a user profile by username, ignoring case.
+func (r *SQLiteProfileRepository) GetProfileByUsername(ctx context.Context, username string) (*Profile, error) {
+	profile := &Profile{}
+	err := r.db.QueryRowContext(ctx, "SELECT id, user_id, email, username, bio, created_at, updated_at FROM profiles WHERE username = $1 COLLATE NOCASE", username).
+		Scan(&profile.Id, &profile.UserId, &profile.Email, &profile.UserName, &profile.Bio, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("GetProfileByUsername: %w", err)
+	}
+	return profile, nil
+}
+
+// GetUsernamesWithPrefix returns the usernames starting with prefix, ignoring
+// case. prefix must not contain LIKE wildcards.
+func (r *SQLiteProfileRepository) GetUsernamesWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT username FROM profiles WHERE username LIKE $1 || '%'", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("GetUsernamesWithPrefix: %w", err)
+	}
+	defer rows.Close()
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("GetUsernamesWithPrefix rows.Scan: %w", err)
+		}
+		usernames = append(usernames, username)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUsernamesWithPrefix rows.Err: %w", err)
+	}
+	return usernames, nil
+}
+
+// UpdateProfile saves a profile's bio and username. A replaced username is
+// kept in username_history so old links can be redirected.
+func (r *SQLiteProfileRepository) UpdateProfile(ctx context.Context, p *Profile) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("UpdateProfile BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldUsername sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT username FROM profiles WHERE user_id = $1", p.UserId).Scan(&oldUsername)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProfileNotFound
+		}
+		return fmt.Errorf("UpdateProfile error: %w", err)
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx,
 		"UPDATE profiles SET bio = $1, username = $2, updated_at = $3 WHERE user_id = $4",
 		p.Bio,
 		p.UserName,
-		time.Now(),
+		now,
 		p.UserId,
 	)
 	if err != nil {
+		if isUsernameTaken(err) {
+			return ErrUsernameTaken
+		}
 		return fmt.Errorf("UpdateProfile error: %w", err)
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return ErrProfileNotFound
+
+	if oldUsername.String != "" && !strings.EqualFold(oldUsername.String, p.UserName) {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO username_history (username, user_id, changed_at) VALUES ($1, $2, $3) ON CONFLICT (username) DO UPDATE SET user_id = excluded.user_id, changed_at = excluded.changed_at",
+			oldUsername.String,
+			p.UserId,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("UpdateProfile username_history: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("UpdateProfile Commit: %w", err)
 	}
 	return nil
 }
 
-func deleteProfile(userId string) error {
-	_, err := db.Db.Exec("DELETE FROM profiles WHERE user_id = $1", userId)
+// UpdateEmail changes a profile's email and records the change in
+// profile_email_changes, in one transaction. It returns ErrEmailInUse if
+// another profile has the email.
+func (r *SQLiteProfileRepository) UpdateEmail(ctx context.Context, userId, email string) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("uuid.NewRandom: %w", err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("UpdateEmail BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldEmail string
+	err = tx.QueryRowContext(ctx, "SELECT email FROM profiles WHERE user_id = $1", userId).Scan(&oldEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProfileNotFound
+		}
+		return fmt.Errorf("UpdateEmail error: %w", err)
+	}
+	if oldEmail == email {
+		return nil
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, "UPDATE profiles SET email = $1, updated_at = $2 WHERE user_id = $3", email, now, userId)
+	if err != nil {
+		if isEmailInUse(err) {
+			return ErrEmailInUse
+		}
+		return fmt.Errorf("UpdateEmail error: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO profile_email_changes (id, user_id, old_email, new_email, changed_at) VALUES ($1, $2, $3, $4, $5)",
+		id.String(),
+		userId,
+		oldEmail,
+		email,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("UpdateEmail profile_email_changes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("UpdateEmail Commit: %w", err)
+	}
+	return nil
+}
+
+// GetUsernameRedirect returns the current username of whoever last gave up
+// username, or ErrProfileNotFound if nobody has.
+func (r *SQLiteProfileRepository) GetUsernameRedirect(ctx context.Context, username string) (string, error) {
+	var current string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT p.username FROM username_history h JOIN profiles p ON p.user_id = h.user_id WHERE h.username = $1",
+		username,
+	).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrProfileNotFound
+		}
+		return "", fmt.Errorf("GetUsernameRedirect: %w", err)
+	}
+	return current, nil
+}
+
+// DeleteProfile removes a profile and the redirects from its old usernames,
+// and records the deletion so the profile is not provisioned again. Its rows
+// in profile_email_changes are kept as the audit trail.
+func (r *SQLiteProfileRepository) DeleteProfile(ctx context.Context, userId string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DeleteProfile BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM profiles WHERE user_id = $1", userId)
 	if err != nil {
 		return fmt.Errorf("DeleteProfile error: %w", err)
 	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM username_history WHERE user_id = $1", userId)
+	if err != nil {
+		return fmt.Errorf("DeleteProfile username_history: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO deleted_profiles (user_id, deleted_at) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET deleted_at = excluded.deleted_at",
+			userId, time.Now(),
+		)
+		if err != nil {
+			return fmt.Errorf("DeleteProfile deleted_profiles: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("DeleteProfile Commit: %w", err)
+	}
 	return nil
 }
 
-func getAllProfiles() ([]*Profile, error) {
-	rows, err := db.Db.Query("SELECT * FROM profiles")
+// WasDeleted reports whether userId deleted their profile and has not created
+// one since.
+func (r *SQLiteProfileRepository) WasDeleted(ctx context.Context, userId string) (bool, error) {
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM deleted_profiles WHERE user_id = $1)", userId).Scan(&deleted)
+	if err != nil {
+		return false, fmt.Errorf("WasDeleted error: %w", err)
+	}
+	return deleted, nil
+}
+
+// GetAllProfiles returns one page of profiles, plus one extra row if there is
+// another page (see page.Trim).
+func (r *SQLiteProfileRepository) GetAllProfiles(ctx context.Context, p page.Params) ([]*Profile, error) {
+	after, args := p.Where(1)
+	rows, err := r.db.QueryContext(ctx, "SELECT id, user_id, email, username, bio, created_at, updated_at FROM profiles WHERE "+after+" "+p.OrderBy(), args...)
 	if err != nil {
 		return nil, fmt.Errorf("GetAllProfiles error: %w", err)
 	}
@@ -100,3 +327,22 @@ func getAllProfiles() ([]*Profile, error) {
 
 	return profiles, nil
 }
+
+// isUsernameTaken reports whether err is a violation of the unique index on
+// profiles.username.
+func isUsernameTaken(err error) bool {
+	return isUniqueViolation(err, "profiles.username")
+}
+
+// isEmailInUse reports whether err is a violation of the unique constraint on
+// profiles.email.
+func isEmailInUse(err error) bool {
+	return isUniqueViolation(err, "profiles.email")
+}
+
+func isUniqueViolation(err error, column string) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), column)
+}
@@ -1,31 +1,42 @@
 package profile
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"log"
 	"os"
 	"testing"
 
 	"github.com/cprime50/fire-go/db"
+	"github.com/cprime50/fire-go/page"
+)
+
+var (
+	testDb *sql.DB
+	repo   ProfileRepository
 )
 
 func TestMain(m *testing.M) {
 
 	log.Println("Running tests...")
-	Db, err := db.ConnectTest()
+	var err error
+	testDb, err = db.ConnectTest()
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = db.Migrate(Db)
+	err = db.Migrate(testDb)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Db.Close()
+	defer testDb.Close()
+	repo = NewProfileRepository(testDb)
 
 	os.Exit(m.Run())
 }
 
 func TestCreateProfile(t *testing.T) {
+	ctx := context.Background()
 	clearProfiles()
 
 	// Test case 1: Insert a valid profile
@@ -35,9 +46,9 @@ func TestCreateProfile(t *testing.T) {
 		UserName: "Username1",
 		Bio:      "test bio 1",
 	}
-	err := createProfile(profile)
+	err := repo.CreateProfile(ctx, profile)
 	if err != nil {
-		t.Errorf("createProfile error: %v", err)
+		t.Errorf("CreateProfile error: %v", err)
 	}
 
 	// Test case 2: Insert a second valid profile
@@ -47,19 +58,20 @@ func TestCreateProfile(t *testing.T) {
 		UserName: "Username2",
 		Bio:      "test bio 2",
 	}
-	err = createProfile(profile2)
+	err = repo.CreateProfile(ctx, profile2)
 	if err != nil {
-		t.Errorf("createProfile error: %v", err)
+		t.Errorf("CreateProfile error: %v", err)
 	}
 
 	// Test case 3: Insert a profile that already exists
-	err = createProfile(profile)
+	err = repo.CreateProfile(ctx, profile)
 	if err == nil {
 		t.Errorf("creating duplicate profile error: %v", err)
 	}
 }
 
 func TestGetProfileByUserId(t *testing.T) {
+	ctx := context.Background()
 	clearProfiles()
 
 	// Test case 1: Select a profile by id
@@ -69,26 +81,27 @@ func TestGetProfileByUserId(t *testing.T) {
 		UserName: "Username1",
 		Bio:      "test bio 1",
 	}
-	err := createProfile(profile)
+	err := repo.CreateProfile(ctx, profile)
 	if err != nil {
-		t.Errorf("createProfile error: %v", err)
+		t.Errorf("CreateProfile error: %v", err)
 	}
-	gottenProfile, err := getProfileByUserId(profile.UserId)
+	gottenProfile, err := repo.GetProfileByUserId(ctx, profile.UserId)
 	if err != nil {
-		t.Errorf("getProfileByUserId error: %v", err)
+		t.Errorf("GetProfileByUserId error: %v", err)
 	}
 	if gottenProfile.UserId != profile.UserId {
-		t.Errorf("getProfileByUserId error: not equal")
+		t.Errorf("GetProfileByUserId error: not equal")
 	}
 
 	// Test case 2: Select a profile by id that does not exist
-	_, err = getProfileByUserId("not_exist")
+	_, err = repo.GetProfileByUserId(ctx, "not_exist")
 	if !errors.Is(err, ErrProfileNotFound) {
-		t.Errorf("getProfileByUserId error: %v", err)
+		t.Errorf("GetProfileByUserId error: %v", err)
 	}
 }
 
 func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
 	clearProfiles()
 
 	// Test case 1: Update a valid profile
@@ -98,35 +111,36 @@ func TestUpdateProfile(t *testing.T) {
 		UserName: "Username1",
 		Bio:      "test bio 1",
 	}
-	err := createProfile(profile)
+	err := repo.CreateProfile(ctx, profile)
 	if err != nil {
-		t.Errorf("createProfile error: %v", err)
+		t.Errorf("CreateProfile error: %v", err)
 	}
 	newProfile := &Profile{
 		UserId:   profile.UserId,
 		UserName: "New Username",
 		Bio:      "New Bio",
 	}
-	err = updateProfile(newProfile)
+	err = repo.UpdateProfile(ctx, newProfile)
 	if err != nil {
-		t.Errorf("updateProfile error: %v", err)
+		t.Errorf("UpdateProfile error: %v", err)
 	}
-	updatedProfile, _ := getProfileByUserId(profile.UserId)
+	updatedProfile, _ := repo.GetProfileByUserId(ctx, profile.UserId)
 	if updatedProfile.UserName != newProfile.UserName || updatedProfile.Bio != newProfile.Bio {
-		t.Errorf("updateProfile error: not equal")
+		t.Errorf("UpdateProfile error: not equal")
 	}
 
 	// Test case 2: Update a profile that does not exist
 	newProfile = &Profile{
 		UserId: "not_exist",
 	}
-	err = updateProfile(newProfile)
+	err = repo.UpdateProfile(ctx, newProfile)
 	if !errors.Is(err, ErrProfileNotFound) {
-		t.Errorf("updateProfile error: %v", err)
+		t.Errorf("UpdateProfile error: %v", err)
 	}
 }
 
 func TestDeleteProfile(t *testing.T) {
+	ctx := context.Background()
 	clearProfiles()
 
 	// Test case 1: Delete an existing profile
@@ -136,23 +150,38 @@ func TestDeleteProfile(t *testing.T) {
 		UserName: "Username1",
 		Bio:      "test bio 1",
 	}
-	err := createProfile(profile)
+	err := repo.CreateProfile(ctx, profile)
 	if err != nil {
-		t.Errorf("createProfile error: %v", err)
+		t.Errorf("CreateProfile error: %v", err)
 	}
-	err = deleteProfile(profile.UserId)
+	err = repo.DeleteProfile(ctx, profile.UserId)
 	if err != nil {
-		t.Errorf("deleteProfile error: %v", err)
+		t.Errorf("DeleteProfile error: %v", err)
+	}
+	if deleted, err := repo.WasDeleted(ctx, profile.UserId); err != nil || !deleted {
+		t.Errorf("WasDeleted error: expected the deletion to be recorded, got %v, %v", deleted, err)
 	}
 
 	// Test case 2: Delete a profile that does not exist
-	err = deleteProfile("not_exist")
+	err = repo.DeleteProfile(ctx, "not_exist")
 	if err != nil {
 		t.Error("Error, deleting non existent profile error")
 	}
+	if deleted, _ := repo.WasDeleted(ctx, "not_exist"); deleted {
+		t.Error("WasDeleted error: recorded the deletion of a profile that did not exist")
+	}
+
+	// Test case 3: Creating the profile again clears the record
+	if err := repo.CreateProfile(ctx, profile); err != nil {
+		t.Fatalf("CreateProfile error: %v", err)
+	}
+	if deleted, _ := repo.WasDeleted(ctx, profile.UserId); deleted {
+		t.Error("WasDeleted error: expected the deletion to be forgotten")
+	}
 }
 
 func TestGetAllProfiles(t *testing.T) {
+	ctx := context.Background()
 	clearProfiles()
 
 	// Insert profiles
@@ -162,27 +191,124 @@ func TestGetAllProfiles(t *testing.T) {
 		UserName: "Username1",
 		Bio:      "test bio 1",
 	}
-	_ = createProfile(profile1)
+	_ = repo.CreateProfile(ctx, profile1)
 	profile2 := &Profile{
 		UserId:   "test2",
 		Email:    "test2@email.com",
 		UserName: "Username2",
 		Bio:      "test bio 2",
 	}
-	_ = createProfile(profile2)
+	_ = repo.CreateProfile(ctx, profile2)
 
 	// Get profiles
-	gottenProfiles, err := getAllProfiles()
+	gottenProfiles, err := repo.GetAllProfiles(ctx, page.Params{Limit: page.DefaultLimit, Sort: page.Newest})
 	if err != nil {
-		t.Errorf("getAllProfiles error: %v", err)
+		t.Errorf("GetAllProfiles error: %v", err)
 	}
 	if len(gottenProfiles) != 2 {
-		t.Errorf("getAllProfiles error: expected 2 profiles, got %d", len(gottenProfiles))
+		t.Errorf("GetAllProfiles error: expected 2 profiles, got %d", len(gottenProfiles))
+	}
+}
+
+func TestUniqueUsername(t *testing.T) {
+	ctx := context.Background()
+	clearProfiles()
+
+	// Test case 1: a username taken in another case cannot be reused
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test1", Email: "test1@email.com", UserName: "Username1"})
+	err := repo.CreateProfile(ctx, &Profile{UserId: "test2", Email: "test2@email.com", UserName: "USERNAME1"})
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("CreateProfile error: expected ErrUsernameTaken, got %v", err)
+	}
+
+	// Test case 2: nor taken over by an update
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test2", Email: "test2@email.com", UserName: "Username2"})
+	err = repo.UpdateProfile(ctx, &Profile{UserId: "test2", UserName: "username1"})
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("UpdateProfile error: expected ErrUsernameTaken, got %v", err)
+	}
+
+	// Test case 3: lookups by username and prefix ignore case
+	if p, err := repo.GetProfileByUsername(ctx, "USERNAME2"); err != nil || p.UserId != "test2" {
+		t.Errorf("GetProfileByUsername error: got %+v, %v", p, err)
+	}
+	if names, err := repo.GetUsernamesWithPrefix(ctx, "userNAME"); err != nil || len(names) != 2 {
+		t.Errorf("GetUsernamesWithPrefix error: expected 2 usernames, got %v, %v", names, err)
+	}
+}
+
+func TestUsernameRedirect(t *testing.T) {
+	ctx := context.Background()
+	clearProfiles()
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test1", Email: "test1@email.com", UserName: "OldName"})
+
+	// Test case 1: a changed username redirects to the new one
+	if err := repo.UpdateProfile(ctx, &Profile{UserId: "test1", UserName: "NewName"}); err != nil {
+		t.Fatalf("UpdateProfile error: %v", err)
+	}
+	if current, err := repo.GetUsernameRedirect(ctx, "oldname"); err != nil || current != "NewName" {
+		t.Errorf("GetUsernameRedirect error: expected NewName, got %q, %v", current, err)
+	}
+
+	// Test case 2: a later holder of the old username owns it outright
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test2", Email: "test2@email.com", UserName: "OldName"})
+	if p, err := repo.GetProfileByUsername(ctx, "OldName"); err != nil || p.UserId != "test2" {
+		t.Errorf("GetProfileByUsername error: expected test2, got %+v, %v", p, err)
+	}
+
+	// Test case 3: deleting the profile removes its redirects
+	_ = repo.DeleteProfile(ctx, "test1")
+	if _, err := repo.GetUsernameRedirect(ctx, "OldName"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("GetUsernameRedirect error: expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestUpdateEmail(t *testing.T) {
+	ctx := context.Background()
+	clearProfiles()
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test1", Email: "test1@email.com", UserName: "Username1"})
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test2", Email: "test2@email.com", UserName: "Username2"})
+
+	// Test case 1: the email changes and the change is recorded
+	if err := repo.UpdateEmail(ctx, "test1", "new1@email.com"); err != nil {
+		t.Fatalf("UpdateEmail error: %v", err)
+	}
+	if p, _ := repo.GetProfileByUserId(ctx, "test1"); p.Email != "new1@email.com" {
+		t.Errorf("UpdateEmail error: expected new1@email.com, got %s", p.Email)
+	}
+	var oldEmail, newEmail string
+	err := testDb.QueryRow("SELECT old_email, new_email FROM profile_email_changes WHERE user_id = 'test1'").Scan(&oldEmail, &newEmail)
+	if err != nil || oldEmail != "test1@email.com" || newEmail != "new1@email.com" {
+		t.Errorf("UpdateEmail error: unexpected history %s -> %s, %v", oldEmail, newEmail, err)
+	}
+
+	// Test case 2: another profile's email is refused and nothing is recorded
+	if err := repo.UpdateEmail(ctx, "test1", "test2@email.com"); !errors.Is(err, ErrEmailInUse) {
+		t.Errorf("UpdateEmail error: expected ErrEmailInUse, got %v", err)
+	}
+	var changes int
+	_ = testDb.QueryRow("SELECT COUNT(*) FROM profile_email_changes").Scan(&changes)
+	if changes != 1 {
+		t.Errorf("UpdateEmail error: expected 1 recorded change, got %d", changes)
+	}
+
+	// Test case 3: a profile that does not exist
+	if err := repo.UpdateEmail(ctx, "not_exist", "x@email.com"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("UpdateEmail error: expected ErrProfileNotFound, got %v", err)
+	}
+
+	// Test case 4: deleting the profile keeps the recorded changes
+	if err := repo.DeleteProfile(ctx, "test1"); err != nil {
+		t.Fatalf("DeleteProfile error: %v", err)
+	}
+	_ = testDb.QueryRow("SELECT COUNT(*) FROM profile_email_changes WHERE user_id = 'test1'").Scan(&changes)
+	if changes != 1 {
+		t.Errorf("DeleteProfile error: expected the recorded change to be kept, got %d", changes)
 	}
 }
 
 func clearProfiles() {
-	_, err := db.Db.Exec("DELETE FROM profiles")
+	_, err := testDb.Exec("DELETE FROM profiles; DELETE FROM username_history; DELETE FROM profile_email_changes; DELETE FROM deleted_profiles")
 	if err != nil {
 		log.Fatal(err)
 	}
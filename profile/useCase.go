@@ -1,48 +1,82 @@
 package profile
 
 import (
+	"context"
 	"errors"
 	"log"
 	"time"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/policy"
 )
 
 type ProfileService interface {
-	CreateProfile(userID, email string) (*ProfileResponse, error)
-	UpdateProfile(userID, bio, username string) (*ProfileResponse, error)
-	DeleteProfile(userID string, role string, profileId string) error
-	GetProfile(userID string) (*Profile, error)
-	GetAllProfiles() ([]Profile, error)
+	CreateProfile(ctx context.Context, a actor.Actor) (*ProfileResponse, error)
+	UpdateProfile(ctx context.Context, a actor.Actor, bio, username string) (*ProfileResponse, error)
+	UsernameAvailable(ctx context.Context, a actor.Actor, username string) (bool, error)
+	SyncEmail(ctx context.Context, a actor.Actor) error
+	DeleteProfile(ctx context.Context, a actor.Actor, userID string) error
+	WasDeleted(ctx context.Context, userID string) (bool, error)
+	GetProfile(ctx context.Context, a actor.Actor, userID string) (*Profile, error)
+	GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error)
+	GetAllProfiles(ctx context.Context, a actor.Actor, p page.Params) ([]*Profile, string, error)
 }
 
-type ProfileServiceImpl struct{}
+// usernameAttempts is how many usernames CreateProfile tries before failing.
+const usernameAttempts = 3
+
+type ProfileServiceImpl struct {
+	repo ProfileRepository
+}
+
+func NewProfileService(repo ProfileRepository) *ProfileServiceImpl {
+	return &ProfileServiceImpl{repo: repo}
+}
 
-func (s *ProfileServiceImpl) CreateProfile(userID, email string) (*ProfileResponse, error) {
-	existingProfile, err := getProfileByUserId(userID)
+func (s *ProfileServiceImpl) CreateProfile(ctx context.Context, a actor.Actor) (*ProfileResponse, error) {
+	existingProfile, err := s.repo.GetProfileByUserId(ctx, a.UID)
 	if err == nil && existingProfile != nil {
-		log.Printf("Profile already exists for user %s with email %s", userID, email)
+		log.Printf("Profile already exists for user %s with email %s", a.UID, a.Email)
 		return nil, ErrProfileAlreadyExists
 	} else if err != nil && !errors.Is(err, ErrProfileNotFound) {
 		log.Printf("Error checking profile existence: %v", err)
 		return nil, ErrCreateProfile
 	}
 
-	username, err := generateUsername(email)
+	base, err := generateUsername(a.Email)
 	if err != nil {
 		log.Printf("Error generating username: %v", err)
 		return nil, ErrCreateProfile
 	}
-	err = createProfile(&Profile{
-		UserId:   userID,
-		Email:    email,
-		UserName: username,
-		Bio:      "",
-	})
-	if err != nil {
-		log.Printf("Error creating profile: %v", err)
-		return nil, ErrCreateProfile
+	// Another profile can take the chosen username between the lookup and
+	// the insert, so pick again a few times before giving up
+	for attempt := 0; ; attempt++ {
+		taken, err := s.repo.GetUsernamesWithPrefix(ctx, base)
+		if err != nil {
+			log.Printf("Error checking usernames: %v", err)
+			return nil, ErrCreateProfile
+		}
+		err = s.repo.CreateProfile(ctx, &Profile{
+			UserId:   a.UID,
+			Email:    a.Email,
+			UserName: nextUsername(base, taken),
+			Bio:      "",
+		})
+		if err == nil {
+			break
+		}
+		if errors.Is(err, ErrEmailInUse) {
+			log.Printf("Error creating profile for user %s: email %s is used by another profile", a.UID, a.Email)
+			return nil, ErrEmailInUse
+		}
+		if !errors.Is(err, ErrUsernameTaken) || attempt == usernameAttempts-1 {
+			log.Printf("Error creating profile: %v", err)
+			return nil, ErrCreateProfile
+		}
 	}
 
-	createdProfile, err := getProfileByUserId(userID)
+	createdProfile, err := s.repo.GetProfileByUserId(ctx, a.UID)
 	if err != nil {
 		log.Printf("Error retrieving created profile: %v", err)
 		return nil, ErrCreateProfile
@@ -53,14 +87,31 @@ func (s *ProfileServiceImpl) CreateProfile(userID, email string) (*ProfileRespon
 		Message: "Profile created successfully",
 	}
 
-	log.Printf("Profile created successfully for user %s with email %s", userID, email)
+	log.Printf("Profile created successfully for user %s with email %s", a.UID, a.Email)
 	return response, nil
 }
 
-func (s *ProfileServiceImpl) UpdateProfile(userID, bio, username string) (*ProfileResponse, error) {
+// UpdateProfile changes the caller's bio and username. An empty username
+// keeps the current one.
+func (s *ProfileServiceImpl) UpdateProfile(ctx context.Context, a actor.Actor, bio, username string) (*ProfileResponse, error) {
+	if username == "" {
+		current, err := s.repo.GetProfileByUserId(ctx, a.UID)
+		if err != nil {
+			if errors.Is(err, ErrProfileNotFound) {
+				log.Printf("Error updating profile: %v", err)
+				return nil, ErrProfileNotFound
+			}
+			log.Printf("Error updating profile: %v", err)
+			return nil, ErrUpdateProfile
+		}
+		username = current.UserName
+	} else if err := ValidateUsername(username); err != nil {
+		log.Printf("Error updating profile: invalid username %q: %v", username, err)
+		return nil, err
+	}
 
-	err := updateProfile(&Profile{
-		UserId:    userID,
+	err := s.repo.UpdateProfile(ctx, &Profile{
+		UserId:    a.UID,
 		Bio:       bio,
 		UserName:  username,
 		UpdatedAt: time.Now(),
@@ -70,12 +121,16 @@ func (s *ProfileServiceImpl) UpdateProfile(userID, bio, username string) (*Profi
 			log.Printf("Error updating profile: %v", err)
 			return nil, ErrProfileNotFound
 		}
+		if errors.Is(err, ErrUsernameTaken) {
+			log.Printf("Error updating profile: username %q is taken", username)
+			return nil, ErrUsernameTaken
+		}
 		log.Printf("Error updating profile: %v", err)
 		return nil, ErrUpdateProfile
 
 	}
 
-	updatedProfile, err := getProfileByUserId(userID)
+	updatedProfile, err := s.repo.GetProfileByUserId(ctx, a.UID)
 	if err != nil {
 		log.Printf("Error retrieving profile: %v", err)
 		return nil, ErrProfileNotFound
@@ -86,12 +141,71 @@ func (s *ProfileServiceImpl) UpdateProfile(userID, bio, username string) (*Profi
 		Message: "Profile updated successfully",
 	}
 
-	log.Printf("Profile updated successfully for user %s", userID)
+	log.Printf("Profile updated successfully for user %s", a.UID)
 	return response, nil
 }
 
-func (s *ProfileServiceImpl) DeleteProfile(userID string, role string, profileId string) error {
-	err := deleteProfile(userID)
+// SyncEmail updates the caller's profile to the email in their token, if it
+// has changed. It returns ErrEmailInUse if another profile has that email.
+func (s *ProfileServiceImpl) SyncEmail(ctx context.Context, a actor.Actor) error {
+	if a.UID == "" || a.Email == "" {
+		return nil
+	}
+
+	profile, err := s.repo.GetProfileByUserId(ctx, a.UID)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			return ErrProfileNotFound
+		}
+		log.Printf("SyncEmail: Error retrieving profile for userID %s: %v", a.UID, err)
+		return ErrSyncEmail
+	}
+	if profile.Email == a.Email {
+		return nil
+	}
+
+	if err := s.repo.UpdateEmail(ctx, a.UID, a.Email); err != nil {
+		if errors.Is(err, ErrEmailInUse) {
+			log.Printf("SyncEmail: Error user %s changed email to %s, which another profile uses", a.UID, a.Email)
+			return ErrEmailInUse
+		}
+		if errors.Is(err, ErrProfileNotFound) {
+			return ErrProfileNotFound
+		}
+		log.Printf("SyncEmail: Error updating email for userID %s: %v", a.UID, err)
+		return ErrSyncEmail
+	}
+
+	log.Printf("SyncEmail: Email updated for user %s", a.UID)
+	return nil
+}
+
+// UsernameAvailable reports whether a could take username. It returns
+// ErrInvalidUsername or ErrReservedUsername for names nobody can take. The
+// caller's own username counts as available.
+func (s *ProfileServiceImpl) UsernameAvailable(ctx context.Context, a actor.Actor, username string) (bool, error) {
+	if err := ValidateUsername(username); err != nil {
+		return false, err
+	}
+	existing, err := s.repo.GetProfileByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			return true, nil
+		}
+		log.Printf("UsernameAvailable: Error looking up username %q: %v", username, err)
+		return false, ErrGettingProfile
+	}
+	return existing.UserId == a.UID, nil
+}
+
+// DeleteProfile deletes the profile of userID if a may delete that user's profile.
+func (s *ProfileServiceImpl) DeleteProfile(ctx context.Context, a actor.Actor, userID string) error {
+	if !policy.Can(a, policy.ProfileDelete, policy.OwnedBy(userID)) {
+		log.Printf("DeleteProfile: Error User with id %s and roles %v not allowed access to delete user with id %s", a.UID, a.Roles, userID)
+		return ErrNotAuthorized
+	}
+
+	err := s.repo.DeleteProfile(ctx, userID)
 	if err != nil {
 		if errors.Is(err, ErrProfileNotFound) {
 			log.Printf("Error deleting profile: %v", err)
@@ -101,19 +215,24 @@ func (s *ProfileServiceImpl) DeleteProfile(userID string, role string, profileId
 		return ErrDeletingProfile
 
 	}
-	if role != "admin" {
-		if userID != profileId {
-			log.Printf("DeleteProfile: Error User with id %s and role %s not allowed access to delete user with id %s", userID, role, profileId)
-			return ErrNotAuthorized
-		}
-	}
 
 	log.Printf("DeleteProfile: Profile deleted successfully for userID %s", userID)
 	return nil
 }
 
-func (s *ProfileServiceImpl) GetProfile(userID string) (*Profile, error) {
-	profile, err := getProfileByUserId(userID)
+// WasDeleted reports whether userID's profile was deleted and not created
+// again since.
+func (s *ProfileServiceImpl) WasDeleted(ctx context.Context, userID string) (bool, error) {
+	deleted, err := s.repo.WasDeleted(ctx, userID)
+	if err != nil {
+		log.Printf("Error checking for a deleted profile: %v", err)
+		return false, ErrGettingProfile
+	}
+	return deleted, nil
+}
+
+func (s *ProfileServiceImpl) GetProfile(ctx context.Context, a actor.Actor, userID string) (*Profile, error) {
+	profile, err := s.repo.GetProfileByUserId(ctx, userID)
 	if err != nil {
 		if errors.Is(err, ErrProfileNotFound) {
 			log.Printf("GetProfile: Profile not found for userID %s", userID)
@@ -123,19 +242,62 @@ func (s *ProfileServiceImpl) GetProfile(userID string) (*Profile, error) {
 		return nil, ErrGettingProfile
 	}
 
+	// Emails are only shown to their owner and to those who can read any profile
+	if !policy.Can(a, policy.ProfileRead, policy.OwnedBy(userID)) {
+		profile.Email = ""
+	}
+
 	return profile, nil
 }
 
-func (s *ProfileServiceImpl) GetAllProfiles() ([]*Profile, error) {
-	profiles, err := getAllProfiles()
+// GetPublicProfile returns the public part of the profile with username, for
+// anyone. If the username belonged to someone who has since changed it, it
+// returns a *UsernameChangedError with their current username.
+func (s *ProfileServiceImpl) GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error) {
+	if username == "" {
+		return nil, ErrProfileNotFound
+	}
+
+	profile, err := s.repo.GetProfileByUsername(ctx, username)
+	if err == nil {
+		return profile.Public(), nil
+	}
+	if !errors.Is(err, ErrProfileNotFound) {
+		log.Printf("GetPublicProfile: Error retrieving profile for username %s: %v", username, err)
+		return nil, ErrGettingProfile
+	}
+
+	current, err := s.repo.GetUsernameRedirect(ctx, username)
 	if err != nil {
 		if errors.Is(err, ErrProfileNotFound) {
-			log.Print("GetAllProfiles: No profiles found")
+			log.Printf("GetPublicProfile: Profile not found for username %s", username)
 			return nil, ErrProfileNotFound
 		}
-		log.Printf("GetAllProfiles: Database error: %v", err)
+		log.Printf("GetPublicProfile: Error retrieving redirect for username %s: %v", username, err)
 		return nil, ErrGettingProfile
 	}
+	return nil, &UsernameChangedError{Username: current}
+}
+
+// GetAllProfiles returns one page of profiles and the cursor for the next page.
+func (s *ProfileServiceImpl) GetAllProfiles(ctx context.Context, a actor.Actor, p page.Params) ([]*Profile, string, error) {
+	if !policy.Can(a, policy.ProfileRead, policy.Any) {
+		log.Printf("GetAllProfiles: Error User with id %s and roles %v not allowed to list profiles", a.UID, a.Roles)
+		return nil, "", ErrNotAuthorized
+	}
 
-	return profiles, nil
+	profiles, err := s.repo.GetAllProfiles(ctx, p)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			log.Print("GetAllProfiles: No profiles found")
+			return nil, "", ErrProfileNotFound
+		}
+		log.Printf("GetAllProfiles: Database error: %v", err)
+		return nil, "", ErrGettingProfile
+	}
+
+	profiles, next := page.Trim(profiles, p, func(p *Profile) (time.Time, string) {
+		return p.CreatedAt, p.Id
+	})
+	return profiles, next, nil
 }
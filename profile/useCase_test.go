@@ -0,0 +1,401 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/policy"
+)
+
+// fakeProfileRepository keeps profiles in a map, keyed by user ID, and
+// old usernames in another, keyed by lowercase username.
+type fakeProfileRepository struct {
+	profiles map[string]*Profile
+	history  map[string]string
+	deleted  map[string]bool
+}
+
+func newFakeProfileRepository() *fakeProfileRepository {
+	return &fakeProfileRepository{profiles: map[string]*Profile{}, history: map[string]string{}, deleted: map[string]bool{}}
+}
+
+func (f *fakeProfileRepository) CreateProfile(ctx context.Context, p *Profile) error {
+	if _, ok := f.profiles[p.UserId]; ok {
+		return ErrUniqueConstraintViolation
+	}
+	if _, err := f.GetProfileByUsername(ctx, p.UserName); err == nil {
+		return ErrUsernameTaken
+	}
+	for _, other := range f.profiles {
+		if other.Email == p.Email {
+			return ErrEmailInUse
+		}
+	}
+	stored := *p
+	f.profiles[p.UserId] = &stored
+	delete(f.deleted, p.UserId)
+	return nil
+}
+
+func (f *fakeProfileRepository) GetProfileByUserId(ctx context.Context, userId string) (*Profile, error) {
+	p, ok := f.profiles[userId]
+	if !ok {
+		return nil, ErrProfileNotFound
+	}
+	found := *p
+	return &found, nil
+}
+
+func (f *fakeProfileRepository) GetProfileByUsername(ctx context.Context, username string) (*Profile, error) {
+	for _, p := range f.profiles {
+		if strings.EqualFold(p.UserName, username) {
+			found := *p
+			return &found, nil
+		}
+	}
+	return nil, ErrProfileNotFound
+}
+
+func (f *fakeProfileRepository) GetUsernamesWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	var usernames []string
+	for _, p := range f.profiles {
+		if strings.HasPrefix(strings.ToLower(p.UserName), strings.ToLower(prefix)) {
+			usernames = append(usernames, p.UserName)
+		}
+	}
+	return usernames, nil
+}
+
+func (f *fakeProfileRepository) UpdateProfile(ctx context.Context, p *Profile) error {
+	stored, ok := f.profiles[p.UserId]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	if other, err := f.GetProfileByUsername(ctx, p.UserName); err == nil && other.UserId != p.UserId {
+		return ErrUsernameTaken
+	}
+	if !strings.EqualFold(stored.UserName, p.UserName) {
+		f.history[strings.ToLower(stored.UserName)] = p.UserId
+	}
+	stored.Bio = p.Bio
+	stored.UserName = p.UserName
+	return nil
+}
+
+func (f *fakeProfileRepository) UpdateEmail(ctx context.Context, userId, email string) error {
+	stored, ok := f.profiles[userId]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	for _, other := range f.profiles {
+		if other.UserId != userId && other.Email == email {
+			return ErrEmailInUse
+		}
+	}
+	stored.Email = email
+	return nil
+}
+
+func (f *fakeProfileRepository) GetUsernameRedirect(ctx context.Context, username string) (string, error) {
+	userId, ok := f.history[strings.ToLower(username)]
+	if !ok {
+		return "", ErrProfileNotFound
+	}
+	return f.profiles[userId].UserName, nil
+}
+
+func (f *fakeProfileRepository) DeleteProfile(ctx context.Context, userId string) error {
+	if _, ok := f.profiles[userId]; ok {
+		f.deleted[userId] = true
+	}
+	delete(f.profiles, userId)
+	return nil
+}
+
+func (f *fakeProfileRepository) WasDeleted(ctx context.Context, userId string) (bool, error) {
+	return f.deleted[userId], nil
+}
+
+func (f *fakeProfileRepository) GetAllProfiles(ctx context.Context, p page.Params) ([]*Profile, error) {
+	var profiles []*Profile
+	for _, p := range f.profiles {
+		profiles = append(profiles, p)
+	}
+	if len(profiles) == 0 {
+		return nil, ErrProfileNotFound
+	}
+	return profiles, nil
+}
+
+func TestProfileServiceCreateProfile(t *testing.T) {
+	ctx := context.Background()
+	s := NewProfileService(newFakeProfileRepository())
+
+	// Test case 1: a new profile gets a generated username
+	response, err := s.CreateProfile(ctx, actor.Actor{UID: "test1", Email: "test.one@email.com"})
+	if err != nil {
+		t.Fatalf("CreateProfile error: %v", err)
+	}
+	if response.Profile.UserName != "gophertestone" {
+		t.Errorf("CreateProfile error: unexpected username %s", response.Profile.UserName)
+	}
+
+	// Test case 2: creating it again fails
+	_, err = s.CreateProfile(ctx, actor.Actor{UID: "test1", Email: "test.one@email.com"})
+	if !errors.Is(err, ErrProfileAlreadyExists) {
+		t.Errorf("CreateProfile error: expected ErrProfileAlreadyExists, got %v", err)
+	}
+}
+
+func TestProfileServiceUsernames(t *testing.T) {
+	ctx := context.Background()
+	s := NewProfileService(newFakeProfileRepository())
+	alice := actor.Actor{UID: "alice1", Email: "alice@a.com"}
+	bob := actor.Actor{UID: "bob1", Email: "bob@b.com"}
+
+	// Test case 1: the same email local part gets a numbered suffix
+	want := map[string]string{"alice1": "gopheralice", "alice2": "gopheralice2", "alice3": "gopheralice3"}
+	for _, uid := range []string{"alice1", "alice2", "alice3"} {
+		response, err := s.CreateProfile(ctx, actor.Actor{UID: uid, Email: "alice@" + uid + ".com"})
+		if err != nil {
+			t.Fatalf("CreateProfile error: %v", err)
+		}
+		if response.Profile.UserName != want[uid] {
+			t.Errorf("CreateProfile error: expected %s, got %s", want[uid], response.Profile.UserName)
+		}
+	}
+	if _, err := s.CreateProfile(ctx, bob); err != nil {
+		t.Fatalf("CreateProfile error: %v", err)
+	}
+
+	// Test case 2: invalid and reserved usernames are refused
+	for username, expected := range map[string]error{
+		"ab":                    ErrInvalidUsername,
+		"has space":             ErrInvalidUsername,
+		"_leading":              ErrInvalidUsername,
+		strings.Repeat("a", 31): ErrInvalidUsername,
+		"Admin":                 ErrReservedUsername,
+	} {
+		if _, err := s.UpdateProfile(ctx, bob, "", username); !errors.Is(err, expected) {
+			t.Errorf("UpdateProfile(%q) error: expected %v, got %v", username, expected, err)
+		}
+	}
+
+	// Test case 3: usernames are unique regardless of case
+	if _, err := s.UpdateProfile(ctx, bob, "", "GopherAlice"); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("UpdateProfile error: expected ErrUsernameTaken, got %v", err)
+	}
+	if _, err := s.UpdateProfile(ctx, bob, "", "bob_the-builder"); err != nil {
+		t.Errorf("UpdateProfile error: %v", err)
+	}
+
+	// Test case 4: availability reflects taken names, but not the caller's own
+	for username, expected := range map[string]bool{"gopheralice2": false, "BOB_the-builder": false, "carol": true} {
+		if available, err := s.UsernameAvailable(ctx, alice, username); err != nil || available != expected {
+			t.Errorf("UsernameAvailable(%q) error: expected %v, got %v, %v", username, expected, available, err)
+		}
+	}
+	if available, err := s.UsernameAvailable(ctx, bob, "bob_the-builder"); err != nil || !available {
+		t.Errorf("UsernameAvailable error: expected own username to be available, got %v, %v", available, err)
+	}
+	if _, err := s.UsernameAvailable(ctx, alice, "root"); !errors.Is(err, ErrReservedUsername) {
+		t.Errorf("UsernameAvailable error: expected ErrReservedUsername, got %v", err)
+	}
+}
+
+func TestProfileServiceGetPublicProfile(t *testing.T) {
+	ctx := context.Background()
+	s := NewProfileService(newFakeProfileRepository())
+	user := actor.Actor{UID: "test1", Email: "test1@email.com"}
+	_, _ = s.CreateProfile(ctx, user)
+	_, _ = s.UpdateProfile(ctx, user, "bio", "first")
+	_, _ = s.UpdateProfile(ctx, user, "bio", "second")
+
+	// Test case 1: the current username finds the profile, without the email
+	profile, err := s.GetPublicProfile(ctx, "SECOND")
+	if err != nil || profile.UserId != "test1" || profile.Bio != "bio" {
+		t.Errorf("GetPublicProfile error: got %+v, %v", profile, err)
+	}
+
+	// Test case 2: every old username points at the current one
+	for _, old := range []string{"gophertest1", "first"} {
+		var changed *UsernameChangedError
+		if _, err := s.GetPublicProfile(ctx, old); !errors.As(err, &changed) || changed.Username != "second" {
+			t.Errorf("GetPublicProfile(%q) error: expected a change to second, got %v", old, err)
+		}
+	}
+
+	// Test case 3: unknown usernames are not found
+	if _, err := s.GetPublicProfile(ctx, "nobody"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("GetPublicProfile error: expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestProfileServiceUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	s := NewProfileService(newFakeProfileRepository())
+	user := actor.Actor{UID: "test1", Email: "test1@email.com"}
+	_, _ = s.CreateProfile(ctx, user)
+
+	// Test case 1: update an existing profile
+	response, err := s.UpdateProfile(ctx, user, "new bio", "newname")
+	if err != nil {
+		t.Fatalf("UpdateProfile error: %v", err)
+	}
+	if response.Profile.Bio != "new bio" || response.Profile.UserName != "newname" {
+		t.Errorf("UpdateProfile error: not updated %+v", response.Profile)
+	}
+
+	// Test case 2: update a profile that does not exist
+	_, err = s.UpdateProfile(ctx, actor.Actor{UID: "not_exist"}, "bio", "name")
+	if !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("UpdateProfile error: expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestProfileServiceDeleteProfile(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeProfileRepository()
+	s := NewProfileService(repo)
+	userPermissions := policy.Default().PermissionsFor("user")
+	owner := actor.Actor{UID: "test1", Email: "test1@email.com", Roles: []string{"user"}, Permissions: userPermissions}
+	_, _ = s.CreateProfile(ctx, owner)
+
+	// Test case 1: another user cannot delete the profile
+	err := s.DeleteProfile(ctx, actor.Actor{UID: "test2", Roles: []string{"user"}, Permissions: userPermissions}, "test1")
+	if !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("DeleteProfile error: expected ErrNotAuthorized, got %v", err)
+	}
+	if _, err := repo.GetProfileByUserId(ctx, "test1"); err != nil {
+		t.Error("DeleteProfile error: profile deleted by unauthorized user")
+	}
+
+	// Test case 2: the owner can
+	if err := s.DeleteProfile(ctx, owner, "test1"); err != nil {
+		t.Errorf("DeleteProfile error: %v", err)
+	}
+}
+
+func TestProfileServiceGetProfileHidesEmail(t *testing.T) {
+	ctx := context.Background()
+	s := NewProfileService(newFakeProfileRepository())
+	pol := policy.Default()
+	owner := actor.Actor{UID: "test1", Email: "test1@email.com", Roles: []string{"user"}, Permissions: pol.PermissionsFor("user")}
+	_, _ = s.CreateProfile(ctx, owner)
+
+	// Test case 1: the owner sees their email
+	p, err := s.GetProfile(ctx, owner, "test1")
+	if err != nil || p.Email != "test1@email.com" {
+		t.Errorf("GetProfile error: expected owner to see email, got %+v, %v", p, err)
+	}
+
+	// Test case 2: a moderator does not
+	moderator := actor.Actor{UID: "mod1", Roles: []string{"moderator"}, Permissions: pol.PermissionsFor("moderator")}
+	p, err = s.GetProfile(ctx, moderator, "test1")
+	if err != nil || p.Email != "" {
+		t.Errorf("GetProfile error: expected email hidden from moderator, got %+v, %v", p, err)
+	}
+
+	// Test case 3: an admin does
+	admin := actor.Actor{UID: "admin1", Roles: []string{"admin"}, Permissions: pol.PermissionsFor("admin")}
+	p, err = s.GetProfile(ctx, admin, "test1")
+	if err != nil || p.Email != "test1@email.com" {
+		t.Errorf("GetProfile error: expected admin to see email, got %+v, %v", p, err)
+	}
+}
+
+// countingProfileRepository counts lookups by user ID.
+type countingProfileRepository struct {
+	*fakeProfileRepository
+	lookups int
+}
+
+func (c *countingProfileRepository) GetProfileByUserId(ctx context.Context, userId string) (*Profile, error) {
+	c.lookups++
+	return c.fakeProfileRepository.GetProfileByUserId(ctx, userId)
+}
+
+func TestProvisioner(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingProfileRepository{fakeProfileRepository: newFakeProfileRepository()}
+	p := NewProvisioner(NewProfileService(repo))
+	user := actor.Actor{UID: "test1", Email: "test1@email.com", Roles: []string{"user"}, Permissions: policy.Default().PermissionsFor("user")}
+
+	// Test case 1: the first request creates the profile
+	if err := p.Provision(ctx, user); err != nil {
+		t.Fatalf("Provision error: %v", err)
+	}
+	if _, err := repo.GetProfileByUserId(ctx, user.UID); err != nil {
+		t.Fatalf("Provision error: profile not created: %v", err)
+	}
+
+	// Test case 2: later requests do not touch the database
+	repo.lookups = 0
+	for i := 0; i < 3; i++ {
+		if err := p.Provision(ctx, user); err != nil {
+			t.Fatalf("Provision error: %v", err)
+		}
+	}
+	if repo.lookups != 0 {
+		t.Errorf("Provision error: expected no lookups, got %d", repo.lookups)
+	}
+
+	// Test case 3: an existing profile is remembered without an error
+	_ = repo.CreateProfile(ctx, &Profile{UserId: "test2", Email: "test2@email.com", UserName: "existing"})
+	if err := p.Provision(ctx, actor.Actor{UID: "test2", Email: "test2@email.com"}); err != nil {
+		t.Errorf("Provision error: %v", err)
+	}
+
+	// Test case 4: a deleted profile stays deleted, also after a restart
+	if err := p.DeleteProfile(ctx, user, user.UID); err != nil {
+		t.Fatalf("DeleteProfile error: %v", err)
+	}
+	for _, provisioner := range []*Provisioner{p, NewProvisioner(NewProfileService(repo))} {
+		if err := provisioner.Provision(ctx, user); err != nil {
+			t.Fatalf("Provision error: %v", err)
+		}
+		if _, err := repo.GetProfileByUserId(ctx, user.UID); !errors.Is(err, ErrProfileNotFound) {
+			t.Errorf("Provision error: expected the deleted profile to stay deleted, got %v", err)
+		}
+	}
+
+	// Test case 5: creating the profile again resumes provisioning
+	if _, err := p.CreateProfile(ctx, user); err != nil {
+		t.Fatalf("CreateProfile error: %v", err)
+	}
+	if deleted, _ := repo.WasDeleted(ctx, user.UID); deleted {
+		t.Error("CreateProfile error: expected the deletion to be forgotten")
+	}
+
+	// Test case 6: anonymous callers are left alone
+	if err := p.Provision(ctx, actor.Actor{}); err != nil {
+		t.Errorf("Provision error: %v", err)
+	}
+
+	// Test case 7: a new email in the token is copied to the profile
+	user.Email = "renamed@email.com"
+	if err := p.Provision(ctx, user); err != nil {
+		t.Fatalf("Provision error: %v", err)
+	}
+	if stored, _ := repo.GetProfileByUserId(ctx, user.UID); stored.Email != "renamed@email.com" {
+		t.Errorf("Provision error: expected the new email, got %s", stored.Email)
+	}
+
+	// Test case 8: an email another profile has is refused, and retried later
+	user.Email = "test2@email.com"
+	err := p.Provision(ctx, user)
+	var conflict *middleware.ConflictError
+	if !errors.Is(err, ErrEmailInUse) || !errors.As(err, &conflict) {
+		t.Errorf("Provision error: expected ErrEmailInUse as a conflict, got %v", err)
+	}
+	repo.lookups = 0
+	_ = p.Provision(ctx, user)
+	if repo.lookups == 0 {
+		t.Errorf("Provision error: expected the failed sync to be retried")
+	}
+}
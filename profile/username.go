@@ -0,0 +1,51 @@
+package profile
+
+import "strings"
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+// reservedUsernames cannot be chosen, in any case, as they could be mistaken
+// for staff or clash with routes.
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"root":          true,
+	"system":        true,
+	"moderator":     true,
+	"moderation":    true,
+	"mod":           true,
+	"staff":         true,
+	"support":       true,
+	"help":          true,
+	"automod":       true,
+	"api":           true,
+	"profile":       true,
+	"settings":      true,
+	"me":            true,
+	"null":          true,
+	"undefined":     true,
+}
+
+// ValidateUsername checks a username chosen by a user. Usernames are 3 to 30
+// letters, digits, underscores or hyphens, start with a letter or digit and
+// are compared without regard to case.
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return ErrInvalidUsername
+	}
+	for i, r := range username {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case (r == '_' || r == '-') && i > 0:
+		default:
+			return ErrInvalidUsername
+		}
+	}
+	if reservedUsernames[strings.ToLower(username)] {
+		return ErrReservedUsername
+	}
+	return nil
+}
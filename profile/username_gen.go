@@ -2,21 +2,53 @@ package profile
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
+// usernamePrefix starts every generated username, so generated names never
+// clash with reserved words.
+const usernamePrefix = "gopher"
+
+// generateUsername builds a username from the local part of an email,
+// keeping only letters and digits. The result is a base name that may already
+// be taken; see nextUsername.
 func generateUsername(email string) (string, error) {
 	parts := strings.Split(email, "@")
 	if len(parts) != 2 {
 		return "", fmt.Errorf("invalid email format")
 	}
 
-	usernamePart := parts[0]
-	names := strings.Split(usernamePart, ".")
-	if len(names) == 0 {
-		return "", fmt.Errorf("no names found in email")
+	var b strings.Builder
+	b.WriteString(usernamePrefix)
+	for _, r := range strings.ToLower(parts[0]) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	username := b.String()
+	// Leave room for a collision suffix
+	if len(username) > MaxUsernameLength-4 {
+		username = username[:MaxUsernameLength-4]
 	}
-
-	username := "gopher" + strings.Join(names, "")
 	return username, nil
 }
+
+// nextUsername returns base, or base followed by the smallest number from 2
+// up that is not in taken. taken holds the existing usernames starting with
+// base, in any case.
+func nextUsername(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, name := range taken {
+		used[strings.ToLower(name)] = true
+	}
+	if !used[strings.ToLower(base)] {
+		return base
+	}
+	for n := 2; ; n++ {
+		candidate := base + strconv.Itoa(n)
+		if !used[strings.ToLower(candidate)] {
+			return candidate
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package quote
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/cprime50/fire-go/actor"
 	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/page"
 	"github.com/gin-gonic/gin"
 )
 
 func CreateQuoteHandler(c *gin.Context, s QuoteService) {
-	user, ok := getUserFromCtx(c)
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
@@ -22,10 +28,25 @@ func CreateQuoteHandler(c *gin.Context, s QuoteService) {
 		return
 	}
 
-	err := s.CreateQuote(user.UserID, quoteRequest.Quote)
+	err := s.CreateQuote(c.Request.Context(), a, quoteRequest.Quote, quoteRequest.Tags, quoteRequest.Attribution, quoteRequest.Draft)
 	if err != nil {
 		log.Println("CreateQuoteHandler: Error failed to create quote", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrCreateQuote.Error()})
+		var duplicate *DuplicateError
+		if errors.As(err, &duplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": ErrDuplicateQuote.Error(), "duplicate_of": duplicate.Duplicate})
+			return
+		}
+		if writeAutoRejected(c, err) {
+			return
+		}
+		switch err {
+		case ErrInvalidRequestBody, ErrInvalidTag, ErrTooManyTags, ErrInvalidAttribution:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case ErrNotAuthorized:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrCreateQuote.Error()})
+		}
 		return
 	}
 
@@ -33,13 +54,11 @@ func CreateQuoteHandler(c *gin.Context, s QuoteService) {
 }
 
 func UpdateQuoteHandler(c *gin.Context, service QuoteService) {
-	user, ok := getUserFromCtx(c)
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
 		return
 	}
-	uid := user.UserID
-	role := user.Role
 
 	var requestBody QuoteUpdateRequest
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
@@ -48,13 +67,20 @@ func UpdateQuoteHandler(c *gin.Context, service QuoteService) {
 		return
 	}
 
-	err := service.UpdateQuote(uid, role, requestBody.Id, requestBody.Quote)
+	err := service.UpdateQuote(c.Request.Context(), a, requestBody.Id, requestBody.Quote, requestBody.Tags, requestBody.Attribution)
 	if err != nil {
+		if writeAutoRejected(c, err) {
+			return
+		}
 		switch err {
+		case ErrInvalidRequestBody, ErrInvalidTag, ErrTooManyTags, ErrInvalidAttribution:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		case ErrQuoteNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		case ErrNotAuthorized:
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case ErrInvalidTransition:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -64,17 +90,15 @@ func UpdateQuoteHandler(c *gin.Context, service QuoteService) {
 }
 
 func DeleteQuoteHandler(c *gin.Context, service QuoteService) {
-	user, ok := getUserFromCtx(c)
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
 		return
 	}
-	uid := user.UserID
-	role := user.Role
 
 	id := c.Param("id")
 
-	err := service.DeleteQuote(uid, role, id)
+	err := service.DeleteQuote(c.Request.Context(), a, id)
 	if err != nil {
 		switch err {
 		case ErrQuoteNotFound:
@@ -89,22 +113,67 @@ func DeleteQuoteHandler(c *gin.Context, service QuoteService) {
 	c.JSON(http.StatusOK, gin.H{"message": "Quote deleted successfully"})
 }
 
+// GetQuotesHandler lists quotes. It is served with optional authentication,
+// and anonymous callers only see approved quotes.
 func GetQuotesHandler(c *gin.Context, service QuoteService) {
-	user, ok := getUserFromCtx(c)
+	a, _ := getActorFromCtx(c)
+
+	f, p, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	listQuotes(c, service, a, f, p)
+}
+
+// GetQuotesByTagHandler lists the quotes tagged :slug, taking the same query
+// parameters as GetQuotesHandler.
+func GetQuotesByTagHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-	role := user.Role
 
-	quotes, err := service.GetQuotes(role)
+	f, p, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if f.Tag = Slugify(c.Param("slug")); f.Tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidTag.Error()})
+		return
+	}
+	listQuotes(c, service, a, f, p)
+}
+
+// GetQuotesByAuthorHandler lists the quotes attributed to the author :id,
+// taking the same query parameters as GetQuotesHandler.
+func GetQuotesByAuthorHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	f, p, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.AuthorId = c.Param("id")
+	listQuotes(c, service, a, f, p)
+}
+
+func listQuotes(c *gin.Context, service QuoteService, a actor.Actor, f QuoteFilter, p page.Params) {
+	quotes, next, err := service.GetQuotes(c.Request.Context(), a, f, p)
 	if err != nil {
 		switch err {
 		case ErrQuoteNotFound:
 			log.Print("getQuote: Quotes not found")
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		case ErrNotAuthorized:
-			log.Printf("Unauthorized access for user %s with role %s", user.Email, role)
+			log.Printf("Unauthorized access for user %s with roles %v", a.Email, a.Roles)
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		default:
 			log.Printf("getQuote: Error getting quotes %v", err)
@@ -112,20 +181,364 @@ func GetQuotesHandler(c *gin.Context, service QuoteService) {
 		}
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"quotes": quotes})
+	c.JSON(http.StatusOK, gin.H{"quotes": quotes, "next_cursor": next})
 }
 
-func GetQuotesByUserIdHandler(c *gin.Context, service QuoteService) {
-	user, ok := getUserFromCtx(c)
+// SearchQuotesHandler serves ?q=words&limit=n.
+func SearchQuotesHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	p, err := page.Parse(c.Query("limit"), "", "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := service.SearchQuotes(c.Request.Context(), a, c.Query("q"), p.Limit)
+	if err != nil {
+		switch err {
+		case ErrInvalidRequestBody:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain at least one word"})
+		case ErrQuoteNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// GetTagsHandler serves the tag cloud: ?limit=n tags with their approved
+// quote counts.
+func GetTagsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	p, err := page.Parse(c.Query("limit"), "", "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, err := service.GetTagCounts(c.Request.Context(), a, p.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+func RenameTagHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var requestBody RenameTagRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	if err := service.RenameTag(c.Request.Context(), a, c.Param("slug"), requestBody.Slug); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tag renamed successfully", "slug": Slugify(requestBody.Slug)})
+}
+
+func MergeTagsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var requestBody MergeTagsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	if err := service.MergeTags(c.Request.Context(), a, requestBody.From, requestBody.Into); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tags merged successfully", "slug": Slugify(requestBody.Into)})
+}
+
+func writeTagError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidTag:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrTagNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrTagExists:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func GetAuthorsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	p, err := page.Parse(c.Query("limit"), c.Query("cursor"), c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	authors, next, err := service.GetAuthors(c.Request.Context(), a, p)
+	if err != nil {
+		writeAuthorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authors": authors, "next_cursor": next})
+}
+
+func GetAuthorHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	author, sources, err := service.GetAuthor(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeAuthorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"author": author, "sources": sources})
+}
+
+func MergeAuthorsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var requestBody MergeAuthorsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	if err := service.MergeAuthors(c.Request.Context(), a, requestBody.From, requestBody.Into); err != nil {
+		writeAuthorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Authors merged successfully", "author_id": requestBody.Into})
+}
+
+func writeAuthorError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidRequestBody:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrAuthorNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func ReportQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var requestBody ReportRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	err := service.ReportQuote(c.Request.Context(), a, c.Param("id"), requestBody.Reason, requestBody.Details)
+	if err != nil {
+		writeReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Quote reported successfully"})
+}
+
+func GetReportSummariesHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	p, err := page.Parse(c.Query("limit"), "", "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summaries, err := service.GetReportSummaries(c.Request.Context(), a, p.Limit)
+	if err != nil {
+		writeReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": summaries})
+}
+
+func GetQuoteReportsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	reports, err := service.GetReports(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+func ResolveReportsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
 		return
 	}
-	uid := user.UserID
-	role := user.Role
+
+	var requestBody ResolveReportsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	err := service.ResolveReports(c.Request.Context(), a, c.Param("id"), requestBody.Action, requestBody.Reason)
+	if err != nil {
+		writeReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reports resolved successfully"})
+}
+
+func writeReportError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidRequestBody, ErrInvalidReport:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrQuoteNotFound, ErrNoOpenReports:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrAlreadyReported, ErrCannotReport, ErrInvalidTransition:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func LikeQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	likes, err := service.LikeQuote(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeLikeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"liked": true, "likes": likes})
+}
+
+func UnlikeQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	likes, err := service.UnlikeQuote(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeLikeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"liked": false, "likes": likes})
+}
+
+// GetLikedQuotesHandler lists the caller's liked quotes, taking limit, cursor
+// and sort.
+func GetLikedQuotesHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	p, err := page.Parse(c.Query("limit"), c.Query("cursor"), c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quotes, next, err := service.GetLikedQuotes(c.Request.Context(), a, p)
+	if err != nil {
+		writeLikeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"quotes": quotes, "next_cursor": next})
+}
+
+func writeLikeError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidRequestBody:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrQuoteNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrCannotLike:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetQuotesByUserIdHandler lists a user's quotes. It is served with optional
+// authentication, and anonymous callers only see approved quotes.
+func GetQuotesByUserIdHandler(c *gin.Context, service QuoteService) {
+	a, _ := getActorFromCtx(c)
 	requestedUserId := c.Param("profile-id")
 
-	quotes, err := service.GetQuotesByUserId(uid, role, requestedUserId)
+	f, p, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quotes, next, err := service.GetQuotesByUserId(c.Request.Context(), a, requestedUserId, f, p)
 	if err != nil {
 		switch err {
 		case ErrQuoteNotFound:
@@ -137,26 +550,26 @@ func GetQuotesByUserIdHandler(c *gin.Context, service QuoteService) {
 		}
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"quotes": quotes})
+	c.JSON(http.StatusOK, gin.H{"quotes": quotes, "next_cursor": next})
 }
 
 func ApproveQuoteHandler(c *gin.Context, service QuoteService) {
-	user, ok := getUserFromCtx(c)
+	a, ok := getActorFromCtx(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
 		return
 	}
-	uid := user.UserID
-	role := user.Role
 	quoteId := c.Param("id")
 
-	err := service.ApproveQuote(uid, role, quoteId)
+	err := service.ApproveQuote(c.Request.Context(), a, quoteId)
 	if err != nil {
 		switch err {
 		case ErrQuoteNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		case ErrNotAuthorized:
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case ErrInvalidTransition:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -165,24 +578,242 @@ func ApproveQuoteHandler(c *gin.Context, service QuoteService) {
 	c.JSON(http.StatusOK, gin.H{"message": "Quote approved successfully"})
 }
 
+func RejectQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+	quoteId := c.Param("id")
+
+	var requestBody RejectQuoteRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	err := service.RejectQuote(c.Request.Context(), a, quoteId, requestBody.Reason)
+	if err != nil {
+		switch err {
+		case ErrInvalidRequestBody:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case ErrQuoteNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case ErrNotAuthorized:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case ErrInvalidTransition:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quote rejected successfully"})
+}
+
+func SubmitQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	err := service.SubmitQuote(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeTransitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quote submitted for review"})
+}
+
+func WithdrawQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	err := service.WithdrawQuote(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeTransitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quote withdrawn successfully"})
+}
+
+func GetRevisionsHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	revisions, err := service.GetRevisions(c.Request.Context(), a, c.Param("id"))
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// GetRevisionDiffHandler serves ?from=N&to=M, comparing two revision numbers.
+func GetRevisionDiffHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be revision numbers"})
+		return
+	}
+
+	diff, err := service.DiffRevisions(c.Request.Context(), a, c.Param("id"), from, to)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+func RollbackQuoteHandler(c *gin.Context, service QuoteService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	var requestBody RollbackQuoteRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		log.Println("Error binding incoming json data", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidRequestBody.Error()})
+		return
+	}
+
+	err := service.RollbackQuote(c.Request.Context(), a, c.Param("id"), requestBody.Revision)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quote rolled back successfully", "revision": requestBody.Revision})
+}
+
+func writeRevisionError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidRequestBody:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrQuoteNotFound, ErrRevisionNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrInvalidTransition, ErrRevisionNotApproved:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func writeTransitionError(c *gin.Context, err error) {
+	switch err {
+	case ErrInvalidRequestBody:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrQuoteNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrInvalidTransition:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func GetUnapprovedQuotesHandler(c *gin.Context, service QuoteService) {
-	unapprovedQuotes, err := service.GetUnapprovedQuotes()
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	f, p, err := parseListQuery(c, SortRisk)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	unapprovedQuotes, next, err := service.GetUnapprovedQuotes(c.Request.Context(), a, f, p)
 	if err != nil {
 		switch err {
 		case ErrQuoteNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": "No unapproved quotes found"})
+		case ErrNotAuthorized:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get unapproved quotes"})
 		}
 		return
 	}
-	c.JSON(http.StatusOK, unapprovedQuotes)
+	c.JSON(http.StatusOK, gin.H{"quotes": unapprovedQuotes, "next_cursor": next})
+}
+
+// parseListQuery reads the paging and filter query parameters shared by quote
+// listings: limit, cursor, sort, user, author, status, tag, from and to. user
+// is the submitter's user ID and author the attributed author's ID. Dates are
+// RFC 3339 timestamps or YYYY-MM-DD days; to is exclusive. extraSorts are
+// accepted on top of the page package's sorts.
+func parseListQuery(c *gin.Context, extraSorts ...string) (QuoteFilter, page.Params, error) {
+	p, err := page.Parse(c.Query("limit"), c.Query("cursor"), c.Query("sort"), extraSorts...)
+	if err != nil {
+		return QuoteFilter{}, page.Params{}, err
+	}
+
+	f := QuoteFilter{
+		UserId:   c.Query("user"),
+		AuthorId: c.Query("author"),
+		Status:   Status(c.Query("status")),
+	}
+	if f.Status != "" && !f.Status.Valid() {
+		return QuoteFilter{}, page.Params{}, fmt.Errorf("invalid status %q", f.Status)
+	}
+	if tag := c.Query("tag"); tag != "" {
+		if f.Tag = Slugify(tag); f.Tag == "" {
+			return QuoteFilter{}, page.Params{}, ErrInvalidTag
+		}
+	}
+	if f.From, err = parseDate(c.Query("from")); err != nil {
+		return QuoteFilter{}, page.Params{}, fmt.Errorf("invalid from date: %w", err)
+	}
+	if f.To, err = parseDate(c.Query("to")); err != nil {
+		return QuoteFilter{}, page.Params{}, fmt.Errorf("invalid to date: %w", err)
+	}
+	return f, p, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func getActorFromCtx(ctx *gin.Context) (actor.Actor, bool) {
+	return middleware.CurrentActor(ctx)
 }
 
-func getUserFromCtx(ctx *gin.Context) (*middleware.User, bool) {
-	user, exists := ctx.Get("user")
-	if !exists {
-		return nil, false
+// writeAutoRejected answers with 422 and the reasons if err is an
+// *AutoRejectedError, and reports whether it did.
+func writeAutoRejected(c *gin.Context, err error) bool {
+	var rejected *AutoRejectedError
+	if !errors.As(err, &rejected) {
+		return false
 	}
-	return user.(*middleware.User), true
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": ErrAutoRejected.Error(), "reasons": rejected.Reasons})
+	return true
 }
@@ -0,0 +1,33 @@
+package quote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseListQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query string) (QuoteFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/quote/?"+query, nil)
+		f, _, err := parseListQuery(c)
+		return f, err
+	}
+
+	// Test case 1: user is the submitter and author the attributed author
+	f, err := parse("user=uid1&author=author1&status=approved&tag=Software%20Engineering")
+	if err != nil {
+		t.Fatalf("parseListQuery error: %v", err)
+	}
+	if f.UserId != "uid1" || f.AuthorId != "author1" || f.Status != StatusApproved || f.Tag != "software-engineering" {
+		t.Errorf("parseListQuery error: unexpected filter %+v", f)
+	}
+
+	// Test case 2: an unknown status is refused
+	if _, err := parse("status=published"); err == nil {
+		t.Error("parseListQuery error: expected an invalid status to be refused")
+	}
+}
@@ -0,0 +1,65 @@
+package quote
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxAuthorNameLength  = 200
+	maxSourceTitleLength = 300
+	maxPageLength        = 20
+	// minYear is early enough for any written source.
+	minYear = -3000
+)
+
+// nameKey is what author names and source titles are matched on: lowercase
+// words of letters and digits, so "Mark  Twain" and "mark twain." match.
+func nameKey(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// cleanName trims a name and collapses runs of whitespace.
+func cleanName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// normalizeAttribution cleans up req in place and checks it. An empty request
+// is valid and clears the attribution.
+func normalizeAttribution(req *AttributionRequest) error {
+	req.Author = cleanName(req.Author)
+	req.Source = cleanName(req.Source)
+	req.Page = strings.TrimSpace(req.Page)
+
+	if req.Author == "" {
+		if req.Source != "" || req.Page != "" || req.Born != nil || req.Died != nil || req.Year != nil {
+			return ErrInvalidAttribution
+		}
+		return nil
+	}
+	if nameKey(req.Author) == "" || utf8.RuneCountInString(req.Author) > maxAuthorNameLength {
+		return ErrInvalidAttribution
+	}
+	if req.Source == "" && (req.Year != nil || req.Page != "") {
+		return ErrInvalidAttribution
+	}
+	if req.Source != "" && (nameKey(req.Source) == "" || utf8.RuneCountInString(req.Source) > maxSourceTitleLength) {
+		return ErrInvalidAttribution
+	}
+	if utf8.RuneCountInString(req.Page) > maxPageLength {
+		return ErrInvalidAttribution
+	}
+	for _, year := range []*int{req.Born, req.Died, req.Year} {
+		if year != nil && (*year < minYear || *year > time.Now().Year()) {
+			return ErrInvalidAttribution
+		}
+	}
+	if req.Born != nil && req.Died != nil && *req.Died < *req.Born {
+		return ErrInvalidAttribution
+	}
+	return nil
+}
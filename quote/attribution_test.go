@@ -0,0 +1,20 @@
+package quote
+
+import "testing"
+
+func TestNameKey(t *testing.T) {
+	testCases := []struct {
+		name, want string
+	}{
+		{"Mark Twain", "mark twain"},
+		{"  mark   TWAIN. ", "mark twain"},
+		{"M. Twain", "m twain"},
+		{"Lao-Tzu", "lao tzu"},
+		{"...", ""},
+	}
+	for _, tc := range testCases {
+		if got := nameKey(tc.name); got != tc.want {
+			t.Errorf("nameKey(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
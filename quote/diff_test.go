@@ -0,0 +1,46 @@
+package quote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from, to string
+		want     []DiffChunk
+	}{
+		{
+			name: "unchanged",
+			from: "stay hungry stay foolish",
+			to:   "stay  hungry stay foolish",
+			want: []DiffChunk{{DiffEqual, "stay hungry stay foolish"}},
+		},
+		{
+			name: "word replaced",
+			from: "stay hungry stay foolish",
+			to:   "stay curious stay foolish",
+			want: []DiffChunk{{DiffEqual, "stay"}, {DiffDelete, "hungry"}, {DiffInsert, "curious"}, {DiffEqual, "stay foolish"}},
+		},
+		{
+			name: "words appended",
+			from: "less is more",
+			to:   "less is more - Mies",
+			want: []DiffChunk{{DiffEqual, "less is more"}, {DiffInsert, "- Mies"}},
+		},
+		{
+			name: "from empty",
+			from: "",
+			to:   "hello world",
+			want: []DiffChunk{{DiffInsert, "hello world"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := WordDiff(tc.from, tc.to); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("WordDiff(%q, %q) = %v, want %v", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package quote
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// DuplicateThreshold is the estimated similarity at which a new quote is
+	// refused as a duplicate of an existing one.
+	DuplicateThreshold = 0.9
+	// HintThreshold is the similarity at which moderators are told a quote
+	// may be a duplicate.
+	HintThreshold = 0.6
+
+	shingleSize = 5
+	// The signature is split into minhashBands bands of minhashRows values;
+	// quotes sharing any band are compared. With 16 bands of 4, quotes that
+	// are 60% similar share a band about 9 times in 10.
+	minhashBands = 16
+	minhashRows  = 4
+	minhashSize  = minhashBands * minhashRows
+)
+
+// minhashSeeds are the per-position salts of the signature. They are fixed so
+// stored signatures stay comparable across restarts.
+var minhashSeeds = func() [minhashSize]uint64 {
+	var seeds [minhashSize]uint64
+	x := uint64(0x6a09e667f3bcc909)
+	for i := range seeds {
+		x = splitmix64(x)
+		seeds[i] = x
+	}
+	return seeds
+}()
+
+// Fingerprint identifies the content of a quote. Hash matches exact
+// duplicates after normalization; MinHash estimates similarity to near
+// duplicates and is nil for text with nothing to compare.
+type Fingerprint struct {
+	Hash    string
+	MinHash []uint64
+}
+
+// normalizeText folds case, accents and compatibility forms, turns
+// punctuation into spaces and collapses whitespace, so that
+// "Less is  more!" and "less is more" are the same text.
+func normalizeText(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// fingerprint normalizes text and computes its content hash and MinHash
+// signature over character shingles.
+func fingerprint(text string) Fingerprint {
+	normalized := normalizeText(text)
+	sum := sha256.Sum256([]byte(normalized))
+	fp := Fingerprint{Hash: hex.EncodeToString(sum[:])}
+
+	runes := []rune(normalized)
+	if len(runes) == 0 {
+		return fp
+	}
+	fp.MinHash = make([]uint64, minhashSize)
+	for i := range fp.MinHash {
+		fp.MinHash[i] = ^uint64(0)
+	}
+	// Text shorter than a shingle is a single shingle.
+	for i := 0; i == 0 || i+shingleSize <= len(runes); i++ {
+		end := min(i+shingleSize, len(runes))
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i:end])))
+		base := h.Sum64()
+		for j, seed := range minhashSeeds {
+			if v := splitmix64(base ^ seed); v < fp.MinHash[j] {
+				fp.MinHash[j] = v
+			}
+		}
+	}
+	return fp
+}
+
+// Similarity estimates the Jaccard similarity of the shingles of two texts.
+func (fp Fingerprint) Similarity(other Fingerprint) float64 {
+	if fp.Hash == other.Hash {
+		return 1
+	}
+	if len(fp.MinHash) != minhashSize || len(other.MinHash) != minhashSize {
+		return 0
+	}
+	same := 0
+	for i := range fp.MinHash {
+		if fp.MinHash[i] == other.MinHash[i] {
+			same++
+		}
+	}
+	return float64(same) / minhashSize
+}
+
+// bands hashes each band of the signature for locality-sensitive lookup.
+func (fp Fingerprint) bands() []int64 {
+	if len(fp.MinHash) != minhashSize {
+		return nil
+	}
+	bands := make([]int64, minhashBands)
+	buf := make([]byte, 8)
+	for band := range bands {
+		h := fnv.New64a()
+		for _, v := range fp.MinHash[band*minhashRows : (band+1)*minhashRows] {
+			binary.LittleEndian.PutUint64(buf, v)
+			h.Write(buf)
+		}
+		bands[band] = int64(h.Sum64())
+	}
+	return bands
+}
+
+func encodeMinHash(signature []uint64) []byte {
+	if signature == nil {
+		return nil
+	}
+	buf := make([]byte, 8*len(signature))
+	for i, v := range signature {
+		binary.LittleEndian.PutUint64(buf[8*i:], v)
+	}
+	return buf
+}
+
+func decodeMinHash(buf []byte) ([]uint64, error) {
+	if buf == nil {
+		return nil, nil
+	}
+	if len(buf) != 8*minhashSize {
+		return nil, fmt.Errorf("minhash signature has %d bytes, want %d", len(buf), 8*minhashSize)
+	}
+	signature := make([]uint64, minhashSize)
+	for i := range signature {
+		signature[i] = binary.LittleEndian.Uint64(buf[8*i:])
+	}
+	return signature, nil
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,41 @@
+package quote
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	testCases := []struct {
+		text, want string
+	}{
+		{"Less is more.", "less is more"},
+		{"  LESS   is\tmore!!", "less is more"},
+		{"Café “déjà vu”", "cafe deja vu"},
+		{"ﬁne — ２０２４", "fine 2024"},
+		{"...", ""},
+	}
+	for _, tc := range testCases {
+		if got := normalizeText(tc.text); got != tc.want {
+			t.Errorf("normalizeText(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	original := fingerprint("The only way to do great work is to love what you do.")
+	testCases := []struct {
+		text     string
+		min, max float64
+	}{
+		// Test case 1: the same text once normalized
+		{"the only way to do GREAT work is to love what you do", 1, 1},
+		// Test case 2: one word changed
+		{"The only way to do good work is to love what you do.", HintThreshold, DuplicateThreshold},
+		// Test case 3: a different quote
+		{"Simplicity is prerequisite for reliability.", 0, 0.2},
+	}
+	for _, tc := range testCases {
+		got := original.Similarity(fingerprint(tc.text))
+		if got < tc.min || got > tc.max {
+			t.Errorf("Similarity(%q) = %.2f, want between %.2f and %.2f", tc.text, got, tc.min, tc.max)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package quote
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrQuoteNotFound             = errors.New("quote not found")
@@ -12,7 +16,62 @@ var (
 	ErrDeletingQuote             = errors.New("failed to delete quote")
 	ErrGettingQuote              = errors.New("failed to get quote")
 	ErrApprovingQuote            = errors.New("error approving quote")
+	ErrRejectingQuote            = errors.New("error rejecting quote")
+	ErrInvalidTransition         = errors.New("quote cannot move to that status")
+	ErrRevisionNotFound          = errors.New("revision not found")
+	ErrRevisionNotApproved       = errors.New("only approved revisions can be restored")
+	ErrGettingRevisions          = errors.New("failed to get revisions")
+	ErrRollbackQuote             = errors.New("failed to roll back quote")
+	ErrInvalidTag                = errors.New("tags must contain letters or digits")
+	ErrTooManyTags               = errors.New("a quote can have at most 10 tags")
+	ErrTagNotFound               = errors.New("tag not found")
+	ErrTagExists                 = errors.New("a tag with that name already exists")
+	ErrGettingTags               = errors.New("failed to get tags")
+	ErrUpdatingTag               = errors.New("failed to update tag")
+	ErrInvalidAttribution        = errors.New("invalid attribution")
+	ErrAuthorNotFound            = errors.New("author not found")
+	ErrGettingAuthors            = errors.New("failed to get authors")
+	ErrMergingAuthors            = errors.New("failed to merge authors")
+	ErrDuplicateQuote            = errors.New("this quote has already been submitted")
+	ErrInvalidReport             = errors.New("invalid report")
+	ErrAlreadyReported           = errors.New("you have already reported this quote")
+	ErrCannotReport              = errors.New("only other users' published quotes can be reported")
+	ErrReportingQuote            = errors.New("failed to report quote")
+	ErrNoOpenReports             = errors.New("quote has no open reports")
+	ErrGettingReports            = errors.New("failed to get reports")
+	ErrResolvingReports          = errors.New("failed to resolve reports")
+	ErrCannotLike                = errors.New("only published quotes can be liked")
+	ErrLikingQuote               = errors.New("failed to update like")
+	ErrAutoRejected              = errors.New("quote was rejected by automatic moderation")
 	ErrquoteAlreadyExists        = errors.New("quote already exists")
 	ErrForeignKeyViolation       = errors.New("foreign key violation")
 	ErrUniqueConstraintViolation = errors.New("unique constraint violation")
 )
+
+// DuplicateError is returned when a new quote repeats an existing one. It
+// matches ErrDuplicateQuote with errors.Is.
+type DuplicateError struct {
+	Duplicate
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("quote duplicates %s (similarity %.2f)", e.QuoteId, e.Similarity)
+}
+
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicateQuote
+}
+
+// AutoRejectedError is returned when automatic moderation refuses a quote. It
+// matches ErrAutoRejected with errors.Is.
+type AutoRejectedError struct {
+	Reasons []string
+}
+
+func (e *AutoRejectedError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrAutoRejected, strings.Join(e.Reasons, "; "))
+}
+
+func (e *AutoRejectedError) Is(target error) bool {
+	return target == ErrAutoRejected
+}
@@ -0,0 +1,37 @@
+package quote
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// LikeReconcileInterval is how often the server recounts likes.
+const LikeReconcileInterval = time.Hour
+
+// LikeReconciler recounts like counters and returns how many were wrong.
+// SQLiteQuoteRepository satisfies it.
+type LikeReconciler interface {
+	ReconcileLikeCounts(ctx context.Context) (int, error)
+}
+
+// ReconcileLikes fixes drifted like counters straight away and then every
+// interval until ctx is done. Counters only drift if quote_likes is changed
+// behind the repository's back, so this is a safety net rather than the way
+// counts are kept.
+func ReconcileLikes(ctx context.Context, repo LikeReconciler, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := repo.ReconcileLikeCounts(ctx); err != nil {
+			log.Println("Error reconciling like counts:", err)
+		} else if n > 0 {
+			log.Printf("Reconciled like counts of %d quotes", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
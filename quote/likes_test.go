@@ -0,0 +1,46 @@
+package quote
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeLikeReconciler counts its calls and cancels ctx after the last one.
+type fakeLikeReconciler struct {
+	calls  int
+	stop   int
+	cancel context.CancelFunc
+}
+
+func (f *fakeLikeReconciler) ReconcileLikeCounts(ctx context.Context) (int, error) {
+	f.calls++
+	if f.calls == f.stop {
+		f.cancel()
+	}
+	if f.calls == 1 {
+		return 0, errors.New("database is locked")
+	}
+	return 1, nil
+}
+
+func TestReconcileLikes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := &fakeLikeReconciler{stop: 3, cancel: cancel}
+
+	// Test case 1: it runs straight away, carries on after an error and stops with ctx
+	done := make(chan struct{})
+	go func() {
+		ReconcileLikes(ctx, repo, time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReconcileLikes error: did not stop when ctx was done")
+	}
+	if repo.calls != 3 {
+		t.Errorf("ReconcileLikes error: expected 3 runs, got %d", repo.calls)
+	}
+}
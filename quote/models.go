@@ -1,22 +1,216 @@
 package quote
 
-import "time"
+import (
+	"time"
+
+	"github.com/cprime50/fire-go/automod"
+)
 
 type Quote struct {
+	Id     string `json:"id"`
+	UserId string `json:"user_id"`
+	Quote  string `json:"quote"`
+	Status Status `json:"status"`
+	// Tags are slugs, see Slugify.
+	Tags []string `json:"tags"`
+	Attribution
+	Author *Author `json:"author,omitempty"`
+	Source *Source `json:"source,omitempty"`
+	// Approved mirrors Status == StatusApproved for clients that predate Status.
+	Approved        bool       `json:"approved"`
+	ReviewedBy      string     `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Likes           int        `json:"likes"`
+	// PendingEdit is new text for an approved quote that is waiting for review.
+	// Only set for the author and moderators.
+	PendingEdit *Revision `json:"pending_edit,omitempty"`
+	// PossibleDuplicateOf is the most similar other quote, shown to
+	// moderators in the review queue.
+	PossibleDuplicateOf *Duplicate `json:"possible_duplicate_of,omitempty"`
+	// Automod is the automatic moderation verdict on the text waiting for
+	// review, shown to moderators in the review queue.
+	Automod *automod.Result `json:"automod,omitempty"`
+	// HiddenAt is when reader reports took a published quote back into the
+	// moderation queue. It is cleared once a moderator decides.
+	HiddenAt *time.Time `json:"hidden_at,omitempty"`
+}
+
+// Duplicate is an existing quote similar to another one. Similarity is an
+// estimate between 0 and 1, where 1 is the same text once normalized.
+type Duplicate struct {
+	QuoteId    string  `json:"quote_id"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Revision is one saved version of a quote's text and how moderation treated it.
+type Revision struct {
+	Id       string   `json:"id"`
+	QuoteId  string   `json:"quote_id"`
+	Revision int      `json:"revision"`
+	Quote    string   `json:"quote"`
+	Tags     []string `json:"tags"`
+	Attribution
+	EditedBy        string     `json:"edited_by"`
+	Status          Status     `json:"status"`
+	ReviewedBy      string     `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Attribution is who originally said a quote and where, as opposed to UserId,
+// the member who submitted it. All fields are optional.
+type Attribution struct {
+	AuthorId string `json:"author_id,omitempty"`
+	SourceId string `json:"source_id,omitempty"`
+	Page     string `json:"page,omitempty"`
+}
+
+// Author is a person quotes are attributed to. Born and Died are years.
+type Author struct {
 	Id        string    `json:"id"`
-	UserId    string    `json:"user_id"`
-	Quote     string    `json:"quote"`
-	Approved  bool      `json:"approved"`
+	Name      string    `json:"name"`
+	Born      *int      `json:"born,omitempty"`
+	Died      *int      `json:"died,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Source is a work by an author that quotes are taken from.
+type Source struct {
+	Id        string    `json:"id"`
+	AuthorId  string    `json:"author_id"`
+	Title     string    `json:"title"`
+	Year      *int      `json:"year,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type QuoteDiff struct {
+	QuoteId string      `json:"quote_id"`
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Chunks  []DiffChunk `json:"chunks"`
+}
+
+// SearchResult is a quote matching a search, with the matching part of its
+// text highlighted in Snippet. Lower Rank is a better match.
+type SearchResult struct {
+	*Quote
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+// QuoteFilter narrows a quote listing. Zero fields match everything; To is
+// exclusive.
+type QuoteFilter struct {
+	UserId string
+	Status Status
+	Tag    string
+	// AuthorId is the attributed author, not the submitter.
+	AuthorId string
+	From     time.Time
+	To       time.Time
+	// LikedBy keeps the quotes a user likes.
+	LikedBy string
+	// PendingEdits attaches edits waiting for review to approved quotes.
+	PendingEdits bool
+}
+
 type QuoteRequest struct {
-	Quote string `json:"quote"`
+	Quote       string              `json:"quote"`
+	Tags        []string            `json:"tags"`
+	Attribution *AttributionRequest `json:"attribution"`
+	// Draft keeps the quote out of the moderation queue until it is submitted.
+	Draft bool `json:"draft"`
 }
 
 type QuoteUpdateRequest struct {
 	Id    string `json:"id"`
 	Quote string `json:"quote"`
+	// Tags replaces the quote's tags. Leave it out to keep them.
+	Tags []string `json:"tags"`
+	// Attribution replaces the quote's attribution. Leave it out to keep it,
+	// or send {} to clear it.
+	Attribution *AttributionRequest `json:"attribution"`
+}
+
+// AttributionRequest names who said a quote and where. Authors are matched by
+// name and sources by title within the author, and are created the first time
+// they are named. Born, Died and Year fill in details missing on an existing
+// author or source.
+type AttributionRequest struct {
+	Author string `json:"author"`
+	Born   *int   `json:"born"`
+	Died   *int   `json:"died"`
+	Source string `json:"source"`
+	Year   *int   `json:"year"`
+	Page   string `json:"page"`
+}
+
+type MergeAuthorsRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+// Report is one reader's complaint about a published quote.
+type Report struct {
+	Id         string       `json:"id"`
+	QuoteId    string       `json:"quote_id"`
+	UserId     string       `json:"user_id"`
+	Reason     ReportReason `json:"reason"`
+	Details    string       `json:"details,omitempty"`
+	Status     ReportStatus `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ResolvedBy string       `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time   `json:"resolved_at,omitempty"`
+}
+
+// ReportSummary is the open reports on one quote, for triage.
+type ReportSummary struct {
+	QuoteId        string               `json:"quote_id"`
+	Quote          string               `json:"quote"`
+	Status         Status               `json:"status"`
+	HiddenAt       *time.Time           `json:"hidden_at,omitempty"`
+	OpenReports    int                  `json:"open_reports"`
+	Reasons        map[ReportReason]int `json:"reasons"`
+	LastReportedAt time.Time            `json:"last_reported_at"`
+}
+
+type ReportRequest struct {
+	Reason  ReportReason `json:"reason"`
+	Details string       `json:"details"`
+}
+
+// ResolveReportsRequest closes the open reports on a quote. Action is
+// "dismiss", which keeps the quote published, or "uphold", which rejects it
+// with Reason.
+type ResolveReportsRequest struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// TagCount is how many approved quotes carry a tag.
+type TagCount struct {
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+type RenameTagRequest struct {
+	Slug string `json:"slug"`
+}
+
+type MergeTagsRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+type RejectQuoteRequest struct {
+	Reason string `json:"reason"`
+}
+
+type RollbackQuoteRequest struct {
+	Revision int `json:"revision"`
 }
 
 type QuoteResponse struct {
@@ -0,0 +1,62 @@
+package quote
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ReportReason is why a reader reported a quote.
+type ReportReason string
+
+const (
+	ReasonSpam          ReportReason = "spam"
+	ReasonOffensive     ReportReason = "offensive"
+	ReasonMisattributed ReportReason = "misattributed"
+	ReasonDuplicate     ReportReason = "duplicate"
+	ReasonCopyright     ReportReason = "copyright"
+	// ReasonOther needs details.
+	ReasonOther ReportReason = "other"
+)
+
+var reportReasons = []ReportReason{ReasonSpam, ReasonOffensive, ReasonMisattributed, ReasonDuplicate, ReasonCopyright, ReasonOther}
+
+// Valid reports whether r is a reason readers can give.
+func (r ReportReason) Valid() bool {
+	for _, reason := range reportReasons {
+		if reason == r {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportStatus is where a report is in triage. Reports are open until a
+// moderator dismisses them or upholds them by taking the quote down.
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportDismissed ReportStatus = "dismissed"
+	ReportUpheld    ReportStatus = "upheld"
+)
+
+// Actions a moderator can take on the open reports of a quote.
+const (
+	ResolveDismiss = "dismiss"
+	ResolveUphold  = "uphold"
+)
+
+// upheldReason is the rejection reason when a moderator upholds reports
+// without giving one.
+const upheldReason = "Removed after reader reports"
+
+const maxReportDetailsLength = 500
+
+// normalizeReport trims the details of a report and checks it.
+func normalizeReport(reason ReportReason, details string) (string, error) {
+	details = strings.TrimSpace(details)
+	if !reason.Valid() || utf8.RuneCountInString(details) > maxReportDetailsLength || (reason == ReasonOther && details == "") {
+		return "", ErrInvalidReport
+	}
+	return details, nil
+}
@@ -1,97 +1,265 @@
 package quote
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/cprime50/fire-go/db"
+	"github.com/cprime50/fire-go/automod"
+	"github.com/cprime50/fire-go/page"
 	"github.com/google/uuid"
 )
 
-func createQuote(quote *Quote) error {
+type QuoteRepository interface {
+	CreateQuote(ctx context.Context, quote *Quote) error
+	UpdateQuote(ctx context.Context, quote *Quote, from Status, editedBy string) error
+	DeleteQuote(ctx context.Context, quoteId string) error
+	GetQuoteById(ctx context.Context, quoteId string) (*Quote, error)
+	ListQuotes(ctx context.Context, f QuoteFilter, p page.Params) ([]*Quote, error)
+	SearchQuotes(ctx context.Context, match string, viewerId string, all bool, limit int) ([]*SearchResult, error)
+	UpdateStatus(ctx context.Context, quote *Quote, from Status) error
+	GetUnapprovedQuotes(ctx context.Context, f QuoteFilter, p page.Params) ([]*Quote, error)
+	GetRevisions(ctx context.Context, quoteId string) ([]*Revision, error)
+	GetRevision(ctx context.Context, quoteId string, revision int) (*Revision, error)
+	RestoreRevision(ctx context.Context, rev *Revision, from Status, reviewedBy string) error
+	ProposeEdit(ctx context.Context, rev *Revision) error
+	GetPendingEdit(ctx context.Context, quoteId string) (*Revision, error)
+	ReviewEdit(ctx context.Context, rev *Revision) error
+	GetTagCounts(ctx context.Context, limit int) ([]*TagCount, error)
+	RenameTag(ctx context.Context, from, to string) error
+	MergeTags(ctx context.Context, from, into string) error
+	ResolveAttribution(ctx context.Context, req *AttributionRequest) (Attribution, error)
+	ListAuthors(ctx context.Context, p page.Params) ([]*Author, error)
+	GetAuthor(ctx context.Context, authorId string) (*Author, error)
+	GetSources(ctx context.Context, authorId string) ([]*Source, error)
+	MergeAuthors(ctx context.Context, from, into string) error
+	FindDuplicates(ctx context.Context, fp Fingerprint, excludeId string, minSimilarity float64) ([]*Duplicate, error)
+	SetAutomod(ctx context.Context, quoteId string, result automod.Result) error
+	CountQuotesByStatus(ctx context.Context, userId string) (map[Status]int, error)
+	CreateReport(ctx context.Context, report *Report) (int, error)
+	HideQuote(ctx context.Context, quoteId string, at time.Time) error
+	ResolveReports(ctx context.Context, quoteId string, status ReportStatus, resolvedBy string) (int, error)
+	GetReportSummaries(ctx context.Context, limit int) ([]*ReportSummary, error)
+	GetReports(ctx context.Context, quoteId string) ([]*Report, error)
+	SetLike(ctx context.Context, quoteId, userId string, liked bool) (int, error)
+}
+
+// SQLiteQuoteRepository stores quotes in the quotes table, every version of
+// their text in quote_revisions, their tags in tags and quote_tags, and who
+// they are attributed to in authors and sources.
+type SQLiteQuoteRepository struct {
+	db *sql.DB
+}
+
+func NewQuoteRepository(db *sql.DB) *SQLiteQuoteRepository {
+	return &SQLiteQuoteRepository{db: db}
+}
+
+func (r *SQLiteQuoteRepository) CreateQuote(ctx context.Context, quote *Quote) error {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return fmt.Errorf("CreateQuote uuid.NewRandom: %w", err)
 	}
-	_, err = db.Db.Exec(
-		"INSERT INTO quotes (id, user_id, quote, approved, created_at) VALUES ($1, $2, $3, $4, $5)",
-		id.String(),
-		quote.UserId,
-		quote.Quote,
-		quote.Approved,
-		time.Now(),
-	)
+	now := time.Now()
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO quotes (id, user_id, quote, status, created_at, author_id, source_id, source_page) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			id.String(),
+			quote.UserId,
+			quote.Quote,
+			quote.Status,
+			now,
+			nullString(quote.AuthorId),
+			nullString(quote.SourceId),
+			nullString(quote.Page),
+		)
+		if err != nil {
+			return err
+		}
+		if err := setQuoteTags(ctx, tx, id.String(), quote.Tags); err != nil {
+			return err
+		}
+		if err := setFingerprint(ctx, tx, id.String(), quote.Quote); err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, &Revision{
+			QuoteId:     id.String(),
+			Quote:       quote.Quote,
+			Tags:        quote.Tags,
+			Attribution: quote.Attribution,
+			EditedBy:    quote.UserId,
+			Status:      quote.Status,
+			CreatedAt:   now,
+		})
+	})
 	if err != nil {
 		return fmt.Errorf("CreateQuote error: %w", err)
 	}
+	quote.Id = id.String()
 	return nil
 }
 
-func updateQuote(quote *Quote) error {
-	_, err := db.Db.Exec(
-		"UPDATE quotes SET quote = $1, approved = FALSE, updated_at = $2 WHERE id = $3",
-		quote.Quote,
-		time.Now(),
-		quote.Id,
-	)
+// UpdateQuote replaces the text, tags and attribution of a quote and saves
+// them as a new revision, as long as the quote is still in status from. It
+// returns ErrInvalidTransition when someone else changed the status first.
+// Resubmitting a rejected quote clears the earlier review.
+func (r *SQLiteQuoteRepository) UpdateQuote(ctx context.Context, quote *Quote, from Status, editedBy string) error {
+	now := time.Now()
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE quotes SET quote = $1, status = $2, updated_at = $3, author_id = $4, source_id = $5, source_page = $6 WHERE id = $7 AND status = $8",
+			quote.Quote,
+			quote.Status,
+			now,
+			nullString(quote.AuthorId),
+			nullString(quote.SourceId),
+			nullString(quote.Page),
+			quote.Id,
+			from,
+		)
+		if err != nil {
+			return err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrInvalidTransition
+		}
+		if from == StatusRejected {
+			_, err = tx.ExecContext(ctx, "UPDATE quotes SET reviewed_by = NULL, reviewed_at = NULL, rejection_reason = NULL WHERE id = $1", quote.Id)
+			if err != nil {
+				return err
+			}
+		}
+		if err := setQuoteTags(ctx, tx, quote.Id, quote.Tags); err != nil {
+			return err
+		}
+		if err := setFingerprint(ctx, tx, quote.Id, quote.Quote); err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, &Revision{
+			QuoteId:     quote.Id,
+			Quote:       quote.Quote,
+			Tags:        quote.Tags,
+			Attribution: quote.Attribution,
+			EditedBy:    editedBy,
+			Status:      quote.Status,
+			CreatedAt:   now,
+		})
+	})
 	if err != nil {
 		return fmt.Errorf("UpdateQuote error: %w", err)
 	}
 	return nil
 }
 
-// deletequote
-func deleteQuote(quoteId string) error {
-	_, err := db.Db.Exec(
-		"DELETE FROM quotes WHERE id = $1",
-		quoteId,
-	)
+// DeleteQuote deletes a quote. Its revisions, tags, fingerprints, reports,
+// likes and comments go with it through ON DELETE CASCADE.
+func (r *SQLiteQuoteRepository) DeleteQuote(ctx context.Context, quoteId string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM quotes WHERE id = $1", quoteId)
 	if err != nil {
 		return fmt.Errorf("DeleteQuote error: %w", err)
 	}
 	return nil
 }
 
-func getQuoteById(quoteId string) (*Quote, error) {
-	var quote Quote
-	err := db.Db.QueryRow(
-		"SELECT * FROM quotes WHERE id = $1",
+func (r *SQLiteQuoteRepository) GetQuoteById(ctx context.Context, quoteId string) (*Quote, error) {
+	quote, err := scanQuote(r.db.QueryRowContext(ctx,
+		"SELECT "+quoteColumns+" FROM quotes WHERE id = $1",
 		quoteId,
-	).Scan(
-		&quote.Id,
-		&quote.UserId,
-		&quote.Quote,
-		&quote.Approved,
-		&quote.CreatedAt,
-	)
+	))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrQuoteNotFound
 		}
 		return nil, fmt.Errorf("GetQuoteById error: %w", err)
 	}
-	return &quote, nil
+	if err := r.attachDetails(ctx, []*Quote{quote}); err != nil {
+		return nil, fmt.Errorf("GetQuoteById attachDetails: %w", err)
+	}
+	return quote, nil
 }
 
-// GetQuotesByProfileId retrieves a user quote by user ID.
-func getQuotesByUserId(userId string) ([]*Quote, error) {
-	rows, err := db.Db.Query("SELECT * FROM quotes WHERE user_id = $1", userId)
-	if err != nil {
-		return nil, fmt.Errorf("db.Query: %w", err)
-	}
-	defer rows.Close()
-	quotes, _ := queryQuotes(rows)
-	return quotes, nil
+// UpdateStatus moves a quote to quote.Status and records the review, as long as
+// the quote is still in status from. It returns ErrInvalidTransition when
+// someone else changed the status first.
+//
+// The latest revision follows along: reviews are recorded on it, sending an
+// already reviewed revision back to the queue starts a new one with the
+// current text so the earlier outcome stays in the history, and withdrawing
+// supersedes anything still waiting for review.
+func (r *SQLiteQuoteRepository) UpdateStatus(ctx context.Context, quote *Quote, from Status) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE quotes SET status = $1, reviewed_by = $2, reviewed_at = $3, rejection_reason = $4 WHERE id = $5 AND status = $6",
+			quote.Status,
+			nullString(quote.ReviewedBy),
+			quote.ReviewedAt,
+			nullString(quote.RejectionReason),
+			quote.Id,
+			from,
+		)
+		if err != nil {
+			return fmt.Errorf("UpdateStatus error: %w", err)
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrInvalidTransition
+		}
+
+		latest, err := latestRevision(ctx, tx, quote.Id)
+		if err != nil {
+			return fmt.Errorf("UpdateStatus latestRevision: %w", err)
+		}
+		switch quote.Status {
+		case StatusApproved, StatusRejected:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE quote_revisions SET status = $1, reviewed_by = $2, reviewed_at = $3, rejection_reason = $4 WHERE id = $5",
+				quote.Status,
+				nullString(quote.ReviewedBy),
+				quote.ReviewedAt,
+				nullString(quote.RejectionReason),
+				latest.Id,
+			)
+			if err == nil {
+				_, err = tx.ExecContext(ctx, "UPDATE quotes SET hidden_at = NULL WHERE id = $1", quote.Id)
+			}
+		case StatusPending:
+			if latest.Status == StatusDraft {
+				_, err = tx.ExecContext(ctx, "UPDATE quote_revisions SET status = $1 WHERE id = $2", StatusPending, latest.Id)
+			} else if latest.Status != StatusPending {
+				err = resubmitCurrent(ctx, tx, quote.Id, quote.UserId)
+			}
+		case StatusWithdrawn:
+			err = supersedeRevisions(ctx, tx, quote.Id)
+		}
+		if err != nil {
+			return fmt.Errorf("UpdateStatus revision: %w", err)
+		}
+		return nil
+	})
 }
 
-func getAllQuotes() ([]*Quote, error) {
-	rows, err := db.Db.Query("SELECT * FROM quotes")
+const quoteColumns = "id, user_id, quote, status, reviewed_by, reviewed_at, rejection_reason, created_at, author_id, source_id, source_page, hidden_at, like_count"
+
+// ListQuotes returns one page of the quotes matching f. When f.PendingEdits
+// is set, approved quotes come with any edit still waiting for review.
+func (r *SQLiteQuoteRepository) ListQuotes(ctx context.Context, f QuoteFilter, p page.Params) ([]*Quote, error) {
+	where, args := f.where(1)
+	after, afterArgs := p.Where(len(args) + 1)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+quoteColumns+" FROM quotes WHERE "+where+" AND "+after+" "+p.OrderBy(),
+		append(args, afterArgs...)...,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
 	defer rows.Close()
-
 	quotes, err := queryQuotes(rows)
 	if err != nil {
 		return nil, fmt.Errorf("queryQuotes: %w", err)
@@ -99,60 +267,166 @@ func getAllQuotes() ([]*Quote, error) {
 	if len(quotes) == 0 {
 		return nil, ErrQuoteNotFound
 	}
+	if err := r.attachDetails(ctx, quotes); err != nil {
+		return nil, fmt.Errorf("attachDetails: %w", err)
+	}
+	if f.PendingEdits {
+		if err := r.attachPendingEdits(ctx, quotes); err != nil {
+			return nil, fmt.Errorf("attachPendingEdits: %w", err)
+		}
+	}
 	return quotes, nil
 }
 
-func getAllApprovedQuotes() ([]*Quote, error) {
-	rows, err := db.Db.Query("SELECT * FROM quotes WHERE approved = true")
+// GetUnapprovedQuotes returns one page of the moderation queue: pending
+// quotes and approved quotes with an edit waiting for review. f.Status is
+// ignored.
+func (r *SQLiteQuoteRepository) GetUnapprovedQuotes(ctx context.Context, f QuoteFilter, p page.Params) ([]*Quote, error) {
+	f.Status = ""
+	where, args := f.where(1)
+	after, afterArgs := p.Where(len(args) + 1)
+	orderBy := p.OrderBy()
+	if p.Sort == SortRisk {
+		after, orderBy, afterArgs = riskPage(p, len(args)+1)
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+quoteColumns+`, automod_decision, automod_risk, automod_reasons, automod_scores FROM quotes
+		WHERE (status = 'pending'
+			OR (status = 'approved' AND EXISTS (SELECT 1 FROM quote_revisions r WHERE r.quote_id = quotes.id AND r.status = 'pending')))
+		AND `+where+` AND `+after+` `+orderBy,
+		append(args, afterArgs...)...,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
 	defer rows.Close()
-	quotes, err := queryQuotes(rows)
-	if err != nil {
-		return nil, fmt.Errorf("queryQuotes: %w", err)
+	var quotes []*Quote
+	for rows.Next() {
+		var decision sql.NullString
+		var risk float64
+		var reasons, scores string
+		quote, err := scanQuote(scanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &decision, &risk, &reasons, &scores)...)
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		if decision.Valid {
+			quote.Automod = &automod.Result{Decision: automod.Decision(decision.String), Risk: risk, Reasons: splitReasons(reasons)}
+			if scores != "" {
+				if err := json.Unmarshal([]byte(scores), &quote.Automod.Scores); err != nil {
+					return nil, fmt.Errorf("automod_scores: %w", err)
+				}
+			}
+		}
+		quotes = append(quotes, quote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
 	}
 	if len(quotes) == 0 {
 		return nil, ErrQuoteNotFound
 	}
+	if err := r.attachDetails(ctx, quotes); err != nil {
+		return nil, fmt.Errorf("attachDetails: %w", err)
+	}
+	if err := r.attachPendingEdits(ctx, quotes); err != nil {
+		return nil, fmt.Errorf("attachPendingEdits: %w", err)
+	}
 	return quotes, nil
 }
 
-func getApprovedQuotesByUserId(userId string) ([]*Quote, error) {
-	rows, err := db.Db.Query("SELECT * FROM quotes WHERE user_id = $1 AND approved = true", userId)
+// SearchQuotes runs an FTS5 match expression against quote text, best match
+// first. Unless all is set, only approved quotes and viewerId's own are
+// searched.
+func (r *SQLiteQuoteRepository) SearchQuotes(ctx context.Context, match string, viewerId string, all bool, limit int) ([]*SearchResult, error) {
+	visible := "(q.status = 'approved' OR q.user_id = $2)"
+	if all {
+		visible = "$2 = $2"
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT q.id, q.user_id, q.quote, q.status, q.reviewed_by, q.reviewed_at, q.rejection_reason, q.created_at, q.author_id, q.source_id, q.source_page, q.hidden_at, q.like_count,
+			snippet(quotes_fts, 1, '<mark>', '</mark>', '…', 16),
+			bm25(quotes_fts, 0.0, 1.0) AS rank
+		FROM quotes_fts JOIN quotes q ON q.id = quotes_fts.quote_id
+		WHERE quotes_fts MATCH $1 AND `+visible+`
+		ORDER BY rank, q.created_at DESC
+		LIMIT $3`,
+		match,
+		viewerId,
+		limit,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("db.Query: %w", err)
+		return nil, fmt.Errorf("SearchQuotes error: %w", err)
 	}
 	defer rows.Close()
-	quotes, err := queryQuotes(rows)
-	if err != nil {
-		return nil, fmt.Errorf("queryQuotes: %w", err)
+
+	var results []*SearchResult
+	for rows.Next() {
+		result := &SearchResult{}
+		quote, err := scanQuote(scanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &result.Snippet, &result.Rank)...)
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("SearchQuotes rows.Scan: %w", err)
+		}
+		result.Quote = quote
+		results = append(results, result)
 	}
-	if len(quotes) == 0 {
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SearchQuotes rows.Err: %w", err)
+	}
+	if len(results) == 0 {
 		return nil, ErrQuoteNotFound
 	}
-	return quotes, nil
+	quotes := make([]*Quote, len(results))
+	for i, result := range results {
+		quotes[i] = result.Quote
+	}
+	if err := r.attachDetails(ctx, quotes); err != nil {
+		return nil, fmt.Errorf("SearchQuotes attachDetails: %w", err)
+	}
+	return results, nil
 }
 
-func approveQuote(quoteId string) error {
-	result, err := db.Db.Exec("UPDATE quotes SET approved = TRUE WHERE id = $1", quoteId)
-	if err != nil {
-		return fmt.Errorf("ApproveQuote error: %w", err)
+// where returns the conditions for f, with args numbered from n.
+func (f QuoteFilter) where(n int) (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+	add := func(cond string, arg any) {
+		conds = append(conds, fmt.Sprintf(cond, n+len(args)))
+		args = append(args, arg)
 	}
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return ErrQuoteNotFound
+	if f.UserId != "" {
+		add("user_id = $%d", f.UserId)
 	}
-	return nil
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.AuthorId != "" {
+		add("author_id = $%d", f.AuthorId)
+	}
+	if f.LikedBy != "" {
+		add("id IN (SELECT quote_id FROM quote_likes WHERE user_id = $%d)", f.LikedBy)
+	}
+	if f.Tag != "" {
+		add("id IN (SELECT qt.quote_id FROM quote_tags qt JOIN tags t ON t.id = qt.tag_id WHERE t.slug = $%d)", f.Tag)
+	}
+	// created_at is stored in local time, so bounds are compared the same way
+	if !f.From.IsZero() {
+		add("created_at >= $%d", f.From.In(time.Local))
+	}
+	if !f.To.IsZero() {
+		add("created_at < $%d", f.To.In(time.Local))
+	}
+	return strings.Join(conds, " AND "), args
 }
 
 func queryQuotes(rows *sql.Rows) ([]*Quote, error) {
 	var quotes []*Quote
 	for rows.Next() {
-		quote := &Quote{}
-		quote.CreatedAt = time.Now()
-
-		if err := rows.Scan(&quote.Id, &quote.UserId, &quote.Quote, &quote.Approved, &quote.CreatedAt); err != nil {
+		quote, err := scanQuote(rows)
+		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
 		quotes = append(quotes, quote)
@@ -163,19 +437,1277 @@ func queryQuotes(rows *sql.Rows) ([]*Quote, error) {
 	return quotes, nil
 }
 
-// Get UnapprovedQuote
-func getUnapprovedQuotes() ([]*Quote, error) {
-	rows, err := db.Db.Query("SELECT * FROM quotes WHERE approved = FALSE")
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanFunc adapts a function to scanner, to read extra columns after a quote.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
+
+// scanQuote reads a row selected with the column list used throughout this file.
+func scanQuote(row scanner) (*Quote, error) {
+	quote := &Quote{}
+	var reviewedBy, reason, authorId, sourceId, sourcePage sql.NullString
+	var reviewedAt, hiddenAt sql.NullTime
+	if err := row.Scan(&quote.Id, &quote.UserId, &quote.Quote, &quote.Status, &reviewedBy, &reviewedAt, &reason, &quote.CreatedAt, &authorId, &sourceId, &sourcePage, &hiddenAt, &quote.Likes); err != nil {
+		return nil, err
+	}
+	if hiddenAt.Valid {
+		quote.HiddenAt = &hiddenAt.Time
+	}
+	quote.Attribution = Attribution{AuthorId: authorId.String, SourceId: sourceId.String, Page: sourcePage.String}
+	quote.Approved = quote.Status == StatusApproved
+	quote.Tags = []string{}
+	quote.ReviewedBy = reviewedBy.String
+	quote.RejectionReason = reason.String
+	if reviewedAt.Valid {
+		quote.ReviewedAt = &reviewedAt.Time
+	}
+	return quote, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+const revisionColumns = "id, quote_id, revision, quote, tags, edited_by, status, reviewed_by, reviewed_at, rejection_reason, created_at, author_id, source_id, source_page"
+
+// GetRevisions returns every revision of a quote, oldest first.
+func (r *SQLiteQuoteRepository) GetRevisions(ctx context.Context, quoteId string) ([]*Revision, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+revisionColumns+" FROM quote_revisions WHERE quote_id = $1 ORDER BY revision", quoteId)
 	if err != nil {
 		return nil, fmt.Errorf("db.Query: %w", err)
 	}
 	defer rows.Close()
-	quotes, err := queryQuotes(rows)
+
+	var revisions []*Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, ErrRevisionNotFound
+	}
+	return revisions, nil
+}
+
+func (r *SQLiteQuoteRepository) GetRevision(ctx context.Context, quoteId string, revision int) (*Revision, error) {
+	rev, err := scanRevision(r.db.QueryRowContext(ctx,
+		"SELECT "+revisionColumns+" FROM quote_revisions WHERE quote_id = $1 AND revision = $2",
+		quoteId,
+		revision,
+	))
 	if err != nil {
-		return nil, fmt.Errorf("queryQuotes: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("GetRevision error: %w", err)
 	}
+	return rev, nil
+}
+
+// RestoreRevision makes the text, tags and attribution of rev current again as a new, approved
+// revision, as long as the quote is still in status from. A quote hidden by
+// reports is published again.
+func (r *SQLiteQuoteRepository) RestoreRevision(ctx context.Context, rev *Revision, from Status, reviewedBy string) error {
+	now := time.Now()
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE quotes SET quote = $1, status = $2, reviewed_by = $3, reviewed_at = $4, rejection_reason = NULL, hidden_at = NULL, updated_at = $4, author_id = $5, source_id = $6, source_page = $7 WHERE id = $8 AND status = $9",
+			rev.Quote,
+			StatusApproved,
+			reviewedBy,
+			now,
+			nullString(rev.AuthorId),
+			nullString(rev.SourceId),
+			nullString(rev.Page),
+			rev.QuoteId,
+			from,
+		)
+		if err != nil {
+			return fmt.Errorf("RestoreRevision error: %w", err)
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrInvalidTransition
+		}
+		if err := setQuoteTags(ctx, tx, rev.QuoteId, rev.Tags); err != nil {
+			return fmt.Errorf("RestoreRevision setQuoteTags: %w", err)
+		}
+		if err := setFingerprint(ctx, tx, rev.QuoteId, rev.Quote); err != nil {
+			return fmt.Errorf("RestoreRevision setFingerprint: %w", err)
+		}
+		err = insertRevision(ctx, tx, &Revision{
+			QuoteId:     rev.QuoteId,
+			Quote:       rev.Quote,
+			Tags:        rev.Tags,
+			Attribution: rev.Attribution,
+			EditedBy:    reviewedBy,
+			Status:      StatusApproved,
+			ReviewedBy:  reviewedBy,
+			ReviewedAt:  &now,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			return fmt.Errorf("RestoreRevision insertRevision: %w", err)
+		}
+		return nil
+	})
+}
+
+// ProposeEdit saves new text, tags and attribution for an approved quote as a pending
+// revision. The quote keeps serving what was approved until the edit is
+// reviewed.
+func (r *SQLiteQuoteRepository) ProposeEdit(ctx context.Context, rev *Revision) error {
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		return insertRevision(ctx, tx, &Revision{
+			QuoteId:     rev.QuoteId,
+			Quote:       rev.Quote,
+			Tags:        rev.Tags,
+			Attribution: rev.Attribution,
+			EditedBy:    rev.EditedBy,
+			Status:      StatusPending,
+			CreatedAt:   time.Now(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("ProposeEdit error: %w", err)
+	}
+	return nil
+}
+
+// GetPendingEdit returns the edit of an approved quote that is waiting for
+// review, or ErrRevisionNotFound.
+func (r *SQLiteQuoteRepository) GetPendingEdit(ctx context.Context, quoteId string) (*Revision, error) {
+	rev, err := scanRevision(r.db.QueryRowContext(ctx,
+		`SELECT `+revisionColumns+` FROM quote_revisions
+		WHERE quote_id = $1 AND status = 'pending' AND EXISTS (SELECT 1 FROM quotes WHERE id = $1 AND status = 'approved')`,
+		quoteId,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("GetPendingEdit error: %w", err)
+	}
+	return rev, nil
+}
+
+// ReviewEdit records the outcome of a pending edit. An approved edit becomes
+// the quote's text, tags and attribution. It returns ErrInvalidTransition if the edit is no longer
+// pending.
+func (r *SQLiteQuoteRepository) ReviewEdit(ctx context.Context, rev *Revision) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE quote_revisions SET status = $1, reviewed_by = $2, reviewed_at = $3, rejection_reason = $4 WHERE id = $5 AND status = $6",
+			rev.Status,
+			nullString(rev.ReviewedBy),
+			rev.ReviewedAt,
+			nullString(rev.RejectionReason),
+			rev.Id,
+			StatusPending,
+		)
+		if err != nil {
+			return fmt.Errorf("ReviewEdit error: %w", err)
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrInvalidTransition
+		}
+		if rev.Status != StatusApproved {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE quotes SET quote = $1, reviewed_by = $2, reviewed_at = $3, rejection_reason = NULL, updated_at = $3, author_id = $4, source_id = $5, source_page = $6 WHERE id = $7",
+			rev.Quote,
+			rev.ReviewedBy,
+			rev.ReviewedAt,
+			nullString(rev.AuthorId),
+			nullString(rev.SourceId),
+			nullString(rev.Page),
+			rev.QuoteId,
+		)
+		if err != nil {
+			return fmt.Errorf("ReviewEdit update quote: %w", err)
+		}
+		if err := setQuoteTags(ctx, tx, rev.QuoteId, rev.Tags); err != nil {
+			return fmt.Errorf("ReviewEdit setQuoteTags: %w", err)
+		}
+		if err := setFingerprint(ctx, tx, rev.QuoteId, rev.Quote); err != nil {
+			return fmt.Errorf("ReviewEdit setFingerprint: %w", err)
+		}
+		return nil
+	})
+}
+
+// attachPendingEdits fills in PendingEdit on approved quotes with an edit
+// waiting for review.
+func (r *SQLiteQuoteRepository) attachPendingEdits(ctx context.Context, quotes []*Quote) error {
+	byId := map[string]*Quote{}
+	for _, q := range quotes {
+		if q.Status == StatusApproved {
+			byId[q.Id] = q
+		}
+	}
+	if len(byId) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(byId))
+	for id := range byId {
+		ids = append(ids, id)
+	}
+	in, args := inClause(2, ids)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+revisionColumns+" FROM quote_revisions WHERE status = $1 AND quote_id IN ("+in+")",
+		append([]any{StatusPending}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return err
+		}
+		byId[rev.QuoteId].PendingEdit = rev
+	}
+	return rows.Err()
+}
+
+func (r *SQLiteQuoteRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertRevision appends rev as the next revision of its quote. Earlier
+// revisions that were never reviewed are superseded by it.
+func insertRevision(ctx context.Context, tx *sql.Tx, rev *Revision) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("insertRevision uuid.NewRandom: %w", err)
+	}
+	if err := supersedeRevisions(ctx, tx, rev.QuoteId); err != nil {
+		return fmt.Errorf("insertRevision supersedeRevisions: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO quote_revisions (id, quote_id, revision, quote, edited_by, status, reviewed_by, reviewed_at, rejection_reason, created_at, tags, author_id, source_id, source_page)
+		SELECT $1, $2, COALESCE(MAX(revision), 0) + 1, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13 FROM quote_revisions WHERE quote_id = $2`,
+		id.String(),
+		rev.QuoteId,
+		rev.Quote,
+		rev.EditedBy,
+		rev.Status,
+		nullString(rev.ReviewedBy),
+		rev.ReviewedAt,
+		nullString(rev.RejectionReason),
+		rev.CreatedAt,
+		joinTags(rev.Tags),
+		nullString(rev.AuthorId),
+		nullString(rev.SourceId),
+		nullString(rev.Page),
+	)
+	return err
+}
+
+// resubmitCurrent queues the quote's current text for review again as a new
+// revision.
+func resubmitCurrent(ctx context.Context, tx *sql.Tx, quoteId, editedBy string) error {
+	current, err := scanQuote(tx.QueryRowContext(ctx, "SELECT "+quoteColumns+" FROM quotes WHERE id = $1", quoteId))
+	if err != nil {
+		return err
+	}
+	if current.Tags, err = currentTags(ctx, tx, quoteId); err != nil {
+		return err
+	}
+	return insertRevision(ctx, tx, &Revision{
+		QuoteId:     quoteId,
+		Quote:       current.Quote,
+		Tags:        current.Tags,
+		Attribution: current.Attribution,
+		EditedBy:    editedBy,
+		Status:      StatusPending,
+		CreatedAt:   time.Now(),
+	})
+}
+
+func supersedeRevisions(ctx context.Context, tx *sql.Tx, quoteId string) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE quote_revisions SET status = $1 WHERE quote_id = $2 AND status IN ($3, $4)",
+		StatusSuperseded,
+		quoteId,
+		StatusDraft,
+		StatusPending,
+	)
+	return err
+}
+
+func latestRevision(ctx context.Context, tx *sql.Tx, quoteId string) (*Revision, error) {
+	return scanRevision(tx.QueryRowContext(ctx,
+		"SELECT "+revisionColumns+" FROM quote_revisions WHERE quote_id = $1 ORDER BY revision DESC LIMIT 1",
+		quoteId,
+	))
+}
+
+func scanRevision(row scanner) (*Revision, error) {
+	rev := &Revision{}
+	var tags string
+	var reviewedBy, reason, authorId, sourceId, sourcePage sql.NullString
+	var reviewedAt sql.NullTime
+	if err := row.Scan(&rev.Id, &rev.QuoteId, &rev.Revision, &rev.Quote, &tags, &rev.EditedBy, &rev.Status, &reviewedBy, &reviewedAt, &reason, &rev.CreatedAt, &authorId, &sourceId, &sourcePage); err != nil {
+		return nil, err
+	}
+	rev.Attribution = Attribution{AuthorId: authorId.String, SourceId: sourceId.String, Page: sourcePage.String}
+	rev.Tags = splitTags(tags)
+	rev.ReviewedBy = reviewedBy.String
+	rev.RejectionReason = reason.String
+	if reviewedAt.Valid {
+		rev.ReviewedAt = &reviewedAt.Time
+	}
+	return rev, nil
+}
+
+// attachTags fills in the tags of each quote.
+func (r *SQLiteQuoteRepository) attachTags(ctx context.Context, quotes []*Quote) error {
 	if len(quotes) == 0 {
-		return nil, ErrQuoteNotFound
+		return nil
 	}
-	return quotes, nil
+	byId := make(map[string]*Quote, len(quotes))
+	ids := make([]string, 0, len(quotes))
+	for _, q := range quotes {
+		byId[q.Id] = q
+		ids = append(ids, q.Id)
+	}
+	in, args := inClause(1, ids)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT qt.quote_id, t.slug FROM quote_tags qt JOIN tags t ON t.id = qt.tag_id WHERE qt.quote_id IN ("+in+") ORDER BY t.slug",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var quoteId, slug string
+		if err := rows.Scan(&quoteId, &slug); err != nil {
+			return err
+		}
+		byId[quoteId].Tags = append(byId[quoteId].Tags, slug)
+	}
+	return rows.Err()
+}
+
+// GetTagCounts returns the tags used by approved quotes, most used first.
+func (r *SQLiteQuoteRepository) GetTagCounts(ctx context.Context, limit int) ([]*TagCount, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.slug, COUNT(*) AS uses
+		FROM tags t
+		JOIN quote_tags qt ON qt.tag_id = t.id
+		JOIN quotes q ON q.id = qt.quote_id
+		WHERE q.status = $1
+		GROUP BY t.slug
+		ORDER BY uses DESC, t.slug
+		LIMIT $2`,
+		StatusApproved,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetTagCounts error: %w", err)
+	}
+	defer rows.Close()
+	counts := []*TagCount{}
+	for rows.Next() {
+		tc := &TagCount{}
+		if err := rows.Scan(&tc.Slug, &tc.Count); err != nil {
+			return nil, fmt.Errorf("GetTagCounts scan: %w", err)
+		}
+		counts = append(counts, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetTagCounts rows: %w", err)
+	}
+	return counts, nil
+}
+
+// RenameTag changes the slug of a tag on every quote and revision using it.
+func (r *SQLiteQuoteRepository) RenameTag(ctx context.Context, from, to string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tagId(ctx, tx, from); err != nil {
+			return err
+		}
+		if _, err := tagId(ctx, tx, to); err == nil {
+			return ErrTagExists
+		} else if err != ErrTagNotFound {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE tags SET slug = $1 WHERE slug = $2", to, from); err != nil {
+			return fmt.Errorf("RenameTag update: %w", err)
+		}
+		return replaceRevisionTag(ctx, tx, from, to)
+	})
+}
+
+// MergeTags moves every quote and revision tagged from over to into, then
+// deletes from.
+func (r *SQLiteQuoteRepository) MergeTags(ctx context.Context, from, into string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		fromId, err := tagId(ctx, tx, from)
+		if err != nil {
+			return err
+		}
+		intoId, err := tagId(ctx, tx, into)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO quote_tags (quote_id, tag_id) SELECT quote_id, $1 FROM quote_tags WHERE tag_id = $2",
+			intoId,
+			fromId,
+		)
+		if err != nil {
+			return fmt.Errorf("MergeTags move: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM quote_tags WHERE tag_id = $1", fromId); err != nil {
+			return fmt.Errorf("MergeTags delete quote_tags: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM tags WHERE id = $1", fromId); err != nil {
+			return fmt.Errorf("MergeTags delete tag: %w", err)
+		}
+		return replaceRevisionTag(ctx, tx, from, into)
+	})
+}
+
+func tagId(ctx context.Context, tx *sql.Tx, slug string) (string, error) {
+	var id string
+	err := tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE slug = $1", slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrTagNotFound
+	}
+	return id, err
+}
+
+// setQuoteTags replaces the tags of a quote, creating tags that do not exist
+// yet.
+func setQuoteTags(ctx context.Context, tx *sql.Tx, quoteId string, slugs []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM quote_tags WHERE quote_id = $1", quoteId); err != nil {
+		return err
+	}
+	for _, slug := range slugs {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return fmt.Errorf("setQuoteTags uuid.NewRandom: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO tags (id, slug, created_at) VALUES ($1, $2, $3) ON CONFLICT (slug) DO NOTHING",
+			id.String(),
+			slug,
+			time.Now(),
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO quote_tags (quote_id, tag_id) SELECT $1, id FROM tags WHERE slug = $2",
+			quoteId,
+			slug,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func currentTags(ctx context.Context, tx *sql.Tx, quoteId string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT t.slug FROM quote_tags qt JOIN tags t ON t.id = qt.tag_id WHERE qt.quote_id = $1 ORDER BY t.slug",
+		quoteId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	slugs := []string{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+	return slugs, rows.Err()
+}
+
+// replaceRevisionTag swaps from for to in the tags of every revision, so that
+// pending edits and rollbacks use the new slug.
+func replaceRevisionTag(ctx context.Context, tx *sql.Tx, from, to string) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, tags FROM quote_revisions WHERE ',' || tags || ',' LIKE '%,' || $1 || ',%'",
+		from,
+	)
+	if err != nil {
+		return fmt.Errorf("replaceRevisionTag query: %w", err)
+	}
+	updated := map[string]string{}
+	for rows.Next() {
+		var id, tags string
+		if err := rows.Scan(&id, &tags); err != nil {
+			rows.Close()
+			return fmt.Errorf("replaceRevisionTag scan: %w", err)
+		}
+		slugs := splitTags(tags)
+		for i, slug := range slugs {
+			if slug == from {
+				slugs[i] = to
+			}
+		}
+		slugs, err = normalizeTags(slugs)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		updated[id] = joinTags(slugs)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("replaceRevisionTag rows: %w", err)
+	}
+	for id, tags := range updated {
+		if _, err := tx.ExecContext(ctx, "UPDATE quote_revisions SET tags = $1 WHERE id = $2", tags, id); err != nil {
+			return fmt.Errorf("replaceRevisionTag update: %w", err)
+		}
+	}
+	return nil
+}
+
+// inClause returns "$n, $n+1, ..." placeholders for values, numbered from n.
+func inClause(n int, values []string) (string, []any) {
+	placeholders := make([]string, len(values))
+	args := make([]any, len(values))
+	for i, v := range values {
+		placeholders[i] = fmt.Sprintf("$%d", n+i)
+		args[i] = v
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// attachDetails fills in the tags, author and source of each quote.
+func (r *SQLiteQuoteRepository) attachDetails(ctx context.Context, quotes []*Quote) error {
+	if err := r.attachTags(ctx, quotes); err != nil {
+		return err
+	}
+	return r.attachAttribution(ctx, quotes)
+}
+
+// attachAttribution fills in Author and Source on attributed quotes.
+func (r *SQLiteQuoteRepository) attachAttribution(ctx context.Context, quotes []*Quote) error {
+	authors := map[string]*Author{}
+	sources := map[string]*Source{}
+	for _, q := range quotes {
+		if q.AuthorId != "" {
+			authors[q.AuthorId] = nil
+		}
+		if q.SourceId != "" {
+			sources[q.SourceId] = nil
+		}
+	}
+	if len(authors) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(authors))
+	for id := range authors {
+		ids = append(ids, id)
+	}
+	in, args := inClause(1, ids)
+	found, err := r.queryAuthors(ctx, "SELECT "+authorColumns+" FROM authors WHERE id IN ("+in+")", args...)
+	if err != nil {
+		return err
+	}
+	for _, a := range found {
+		authors[a.Id] = a
+	}
+
+	if len(sources) > 0 {
+		ids = ids[:0]
+		for id := range sources {
+			ids = append(ids, id)
+		}
+		in, args = inClause(1, ids)
+		found, err := r.querySources(ctx, "SELECT "+sourceColumns+" FROM sources WHERE id IN ("+in+")", args...)
+		if err != nil {
+			return err
+		}
+		for _, s := range found {
+			sources[s.Id] = s
+		}
+	}
+
+	for _, q := range quotes {
+		q.Author = authors[q.AuthorId]
+		q.Source = sources[q.SourceId]
+	}
+	return nil
+}
+
+// ResolveAttribution finds the author and source named by req, creating them
+// if needed, and fills in born, died and year where they are still unknown.
+// req must already be normalized.
+func (r *SQLiteQuoteRepository) ResolveAttribution(ctx context.Context, req *AttributionRequest) (Attribution, error) {
+	var at Attribution
+	if req.Author == "" {
+		return at, nil
+	}
+	now := time.Now()
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO authors (id, name, name_key, born, died, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (name_key) DO UPDATE SET born = COALESCE(authors.born, excluded.born), died = COALESCE(authors.died, excluded.died)
+			RETURNING id`,
+			uuid.NewString(),
+			req.Author,
+			nameKey(req.Author),
+			req.Born,
+			req.Died,
+			now,
+		).Scan(&at.AuthorId)
+		if err != nil {
+			return fmt.Errorf("upsert author: %w", err)
+		}
+		if req.Source == "" {
+			return nil
+		}
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO sources (id, author_id, title, title_key, year, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (author_id, title_key) DO UPDATE SET year = COALESCE(sources.year, excluded.year)
+			RETURNING id`,
+			uuid.NewString(),
+			at.AuthorId,
+			req.Source,
+			nameKey(req.Source),
+			req.Year,
+			now,
+		).Scan(&at.SourceId)
+		if err != nil {
+			return fmt.Errorf("upsert source: %w", err)
+		}
+		at.Page = req.Page
+		return nil
+	})
+	if err != nil {
+		return Attribution{}, fmt.Errorf("ResolveAttribution error: %w", err)
+	}
+	return at, nil
+}
+
+// ListAuthors returns one page of authors.
+func (r *SQLiteQuoteRepository) ListAuthors(ctx context.Context, p page.Params) ([]*Author, error) {
+	after, args := p.Where(1)
+	authors, err := r.queryAuthors(ctx, "SELECT "+authorColumns+" FROM authors WHERE "+after+" "+p.OrderBy(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("ListAuthors error: %w", err)
+	}
+	if len(authors) == 0 {
+		return nil, ErrAuthorNotFound
+	}
+	return authors, nil
+}
+
+func (r *SQLiteQuoteRepository) GetAuthor(ctx context.Context, authorId string) (*Author, error) {
+	author, err := scanAuthor(r.db.QueryRowContext(ctx, "SELECT "+authorColumns+" FROM authors WHERE id = $1", authorId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAuthorNotFound
+		}
+		return nil, fmt.Errorf("GetAuthor error: %w", err)
+	}
+	return author, nil
+}
+
+// GetSources returns the works of an author, by title.
+func (r *SQLiteQuoteRepository) GetSources(ctx context.Context, authorId string) ([]*Source, error) {
+	sources, err := r.querySources(ctx, "SELECT "+sourceColumns+" FROM sources WHERE author_id = $1 ORDER BY title_key", authorId)
+	if err != nil {
+		return nil, fmt.Errorf("GetSources error: %w", err)
+	}
+	return sources, nil
+}
+
+// MergeAuthors moves every quote, revision and source of from over to into
+// and deletes from. Sources with the same title are merged too, and into keeps
+// its own lifespan where it has one.
+func (r *SQLiteQuoteRepository) MergeAuthors(ctx context.Context, from, into string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		var born, died sql.NullInt64
+		err := tx.QueryRowContext(ctx, "SELECT born, died FROM authors WHERE id = $1", from).Scan(&born, &died)
+		if err == sql.ErrNoRows {
+			return ErrAuthorNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("MergeAuthors get author: %w", err)
+		}
+		result, err := tx.ExecContext(ctx,
+			"UPDATE authors SET born = COALESCE(born, $1), died = COALESCE(died, $2) WHERE id = $3",
+			born,
+			died,
+			into,
+		)
+		if err != nil {
+			return fmt.Errorf("MergeAuthors update author: %w", err)
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return ErrAuthorNotFound
+		}
+
+		// Sources into already has: repoint quotes and revisions, then drop
+		// the duplicate.
+		rows, err := tx.QueryContext(ctx,
+			`SELECT f.id, i.id, f.year FROM sources f
+			JOIN sources i ON i.author_id = $1 AND i.title_key = f.title_key
+			WHERE f.author_id = $2`,
+			into,
+			from,
+		)
+		if err != nil {
+			return fmt.Errorf("MergeAuthors duplicate sources: %w", err)
+		}
+		type duplicate struct {
+			from, into string
+			year       sql.NullInt64
+		}
+		var duplicates []duplicate
+		for rows.Next() {
+			var d duplicate
+			if err := rows.Scan(&d.from, &d.into, &d.year); err != nil {
+				rows.Close()
+				return fmt.Errorf("MergeAuthors scan source: %w", err)
+			}
+			duplicates = append(duplicates, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("MergeAuthors rows: %w", err)
+		}
+		for _, d := range duplicates {
+			for _, stmt := range []string{
+				"UPDATE quotes SET source_id = $1 WHERE source_id = $2",
+				"UPDATE quote_revisions SET source_id = $1 WHERE source_id = $2",
+			} {
+				if _, err := tx.ExecContext(ctx, stmt, d.into, d.from); err != nil {
+					return fmt.Errorf("MergeAuthors merge source: %w", err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE sources SET year = COALESCE(year, $1) WHERE id = $2", d.year, d.into); err != nil {
+				return fmt.Errorf("MergeAuthors merge source year: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM sources WHERE id = $1", d.from); err != nil {
+				return fmt.Errorf("MergeAuthors delete source: %w", err)
+			}
+		}
+
+		for _, stmt := range []string{
+			"UPDATE sources SET author_id = $1 WHERE author_id = $2",
+			"UPDATE quotes SET author_id = $1 WHERE author_id = $2",
+			"UPDATE quote_revisions SET author_id = $1 WHERE author_id = $2",
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, into, from); err != nil {
+				return fmt.Errorf("MergeAuthors move: %w", err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM authors WHERE id = $1", from); err != nil {
+			return fmt.Errorf("MergeAuthors delete author: %w", err)
+		}
+		return nil
+	})
+}
+
+const (
+	authorColumns = "id, name, born, died, created_at"
+	sourceColumns = "id, author_id, title, year, created_at"
+)
+
+func (r *SQLiteQuoteRepository) queryAuthors(ctx context.Context, query string, args ...any) ([]*Author, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var authors []*Author
+	for rows.Next() {
+		author, err := scanAuthor(rows)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, rows.Err()
+}
+
+func (r *SQLiteQuoteRepository) querySources(ctx context.Context, query string, args ...any) ([]*Source, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sources := []*Source{}
+	for rows.Next() {
+		source := &Source{}
+		var year sql.NullInt64
+		if err := rows.Scan(&source.Id, &source.AuthorId, &source.Title, &year, &source.CreatedAt); err != nil {
+			return nil, err
+		}
+		source.Year = nullInt(year)
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func scanAuthor(row scanner) (*Author, error) {
+	author := &Author{}
+	var born, died sql.NullInt64
+	if err := row.Scan(&author.Id, &author.Name, &born, &died, &author.CreatedAt); err != nil {
+		return nil, err
+	}
+	author.Born = nullInt(born)
+	author.Died = nullInt(died)
+	return author, nil
+}
+
+func nullInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// FindDuplicates returns the approved and pending quotes, other than
+// excludeId, whose text is at least minSimilarity similar to fp, most similar
+// first. Candidates come from the content hash and the MinHash bands.
+func (r *SQLiteQuoteRepository) FindDuplicates(ctx context.Context, fp Fingerprint, excludeId string, minSimilarity float64) ([]*Duplicate, error) {
+	args := []any{excludeId, fp.Hash}
+	var bands []string
+	for band, hash := range fp.bands() {
+		args = append(args, band, hash)
+		bands = append(bands, fmt.Sprintf("(band = $%d AND hash = $%d)", len(args)-1, len(args)))
+	}
+	candidates := "content_hash = $2"
+	if len(bands) > 0 {
+		candidates += " OR id IN (SELECT quote_id FROM quote_minhash_bands WHERE " + strings.Join(bands, " OR ") + ")"
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, content_hash, minhash FROM quotes WHERE id != $1 AND status IN ('approved', 'pending') AND ("+candidates+")",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("FindDuplicates error: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []*Duplicate
+	for rows.Next() {
+		var id string
+		var hash sql.NullString
+		var minhash []byte
+		if err := rows.Scan(&id, &hash, &minhash); err != nil {
+			return nil, fmt.Errorf("FindDuplicates scan: %w", err)
+		}
+		signature, err := decodeMinHash(minhash)
+		if err != nil {
+			return nil, fmt.Errorf("FindDuplicates quote %s: %w", id, err)
+		}
+		similarity := fp.Similarity(Fingerprint{Hash: hash.String, MinHash: signature})
+		if similarity >= minSimilarity {
+			duplicates = append(duplicates, &Duplicate{QuoteId: id, Similarity: similarity})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("FindDuplicates rows: %w", err)
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Similarity > duplicates[j].Similarity
+	})
+	return duplicates, nil
+}
+
+// BackfillFingerprints fingerprints quotes saved before duplicate detection
+// existed and returns how many it updated.
+func (r *SQLiteQuoteRepository) BackfillFingerprints(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, quote FROM quotes WHERE content_hash IS NULL")
+	if err != nil {
+		return 0, fmt.Errorf("BackfillFingerprints error: %w", err)
+	}
+	texts := map[string]string{}
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("BackfillFingerprints scan: %w", err)
+		}
+		texts[id] = text
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("BackfillFingerprints rows: %w", err)
+	}
+
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		for id, text := range texts {
+			if err := setFingerprint(ctx, tx, id, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("BackfillFingerprints: %w", err)
+	}
+	return len(texts), nil
+}
+
+// setFingerprint stores the content hash, MinHash signature and bands of a
+// quote's current text.
+func setFingerprint(ctx context.Context, tx *sql.Tx, quoteId string, text string) error {
+	fp := fingerprint(text)
+	_, err := tx.ExecContext(ctx,
+		"UPDATE quotes SET content_hash = $1, minhash = $2 WHERE id = $3",
+		fp.Hash,
+		encodeMinHash(fp.MinHash),
+		quoteId,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM quote_minhash_bands WHERE quote_id = $1", quoteId); err != nil {
+		return err
+	}
+	for band, hash := range fp.bands() {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO quote_minhash_bands (quote_id, band, hash) VALUES ($1, $2, $3)",
+			quoteId,
+			band,
+			hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SortRisk orders the review queue by automod risk, riskiest first, then
+// newest first.
+const SortRisk = "risk"
+
+// riskPage returns the condition selecting rows after p.After and the
+// ordering for SortRisk. The cursor only holds what page.Cursor does, so the
+// risk of the row it points at is looked up by ID; args are numbered from n.
+func riskPage(p page.Params, n int) (string, string, []any) {
+	orderBy := "ORDER BY automod_risk DESC, created_at DESC, id DESC LIMIT " + strconv.Itoa(p.Limit+1)
+	if p.After == nil {
+		return "1 = 1", orderBy, nil
+	}
+	id, createdAt := fmt.Sprintf("$%d", n), fmt.Sprintf("$%d", n+1)
+	risk := "COALESCE((SELECT automod_risk FROM quotes WHERE id = " + id + "), 0)"
+	return "(automod_risk < " + risk + " OR (automod_risk = " + risk +
+			" AND (created_at < " + createdAt + " OR (created_at = " + createdAt + " AND id < " + id + "))))",
+		orderBy,
+		[]any{p.After.ID, p.After.CreatedAt}
+}
+
+// SetAutomod records the automatic moderation verdict on a quote's latest
+// submitted text.
+func (r *SQLiteQuoteRepository) SetAutomod(ctx context.Context, quoteId string, result automod.Result) error {
+	var scores []byte
+	if len(result.Scores) > 0 {
+		var err error
+		if scores, err = json.Marshal(result.Scores); err != nil {
+			return fmt.Errorf("SetAutomod scores: %w", err)
+		}
+	}
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE quotes SET automod_decision = $1, automod_risk = $2, automod_reasons = $3, automod_scores = $4 WHERE id = $5",
+		result.Decision,
+		result.Risk,
+		strings.Join(result.Reasons, reasonsSeparator),
+		string(scores),
+		quoteId,
+	)
+	if err != nil {
+		return fmt.Errorf("SetAutomod error: %w", err)
+	}
+	return nil
+}
+
+// CountQuotesByStatus returns how many quotes a user has in each status.
+func (r *SQLiteQuoteRepository) CountQuotesByStatus(ctx context.Context, userId string) (map[Status]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM quotes WHERE user_id = $1 GROUP BY status", userId)
+	if err != nil {
+		return nil, fmt.Errorf("CountQuotesByStatus error: %w", err)
+	}
+	defer rows.Close()
+	counts := map[Status]int{}
+	for rows.Next() {
+		var status Status
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("CountQuotesByStatus scan: %w", err)
+		}
+		counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("CountQuotesByStatus rows: %w", err)
+	}
+	return counts, nil
+}
+
+const reasonsSeparator = "\n"
+
+func splitReasons(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, reasonsSeparator)
+}
+
+// CreateReport stores an open report and returns how many open reports its
+// quote now has. It returns ErrAlreadyReported if the reader already has an
+// open report on the quote.
+func (r *SQLiteQuoteRepository) CreateReport(ctx context.Context, report *Report) (int, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return 0, fmt.Errorf("CreateReport uuid.NewRandom: %w", err)
+	}
+	var open int
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM quote_reports WHERE quote_id = $1 AND user_id = $2 AND status = 'open')",
+			report.QuoteId, report.UserId,
+		).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadyReported
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO quote_reports (id, quote_id, user_id, reason, details, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			id.String(),
+			report.QuoteId,
+			report.UserId,
+			report.Reason,
+			report.Details,
+			ReportOpen,
+			report.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM quote_reports WHERE quote_id = $1 AND status = 'open'",
+			report.QuoteId,
+		).Scan(&open)
+	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyReported) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("CreateReport error: %w", err)
+	}
+	report.Id = id.String()
+	return open, nil
+}
+
+// HideQuote takes a published quote back into the moderation queue with its
+// current text. Any edit waiting for review is superseded, so the decision
+// applies to the text readers reported.
+func (r *SQLiteQuoteRepository) HideQuote(ctx context.Context, quoteId string, at time.Time) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		var userId string
+		err := tx.QueryRowContext(ctx,
+			"UPDATE quotes SET status = $1, hidden_at = $2 WHERE id = $3 AND status = $4 RETURNING user_id",
+			StatusPending, at, quoteId, StatusApproved,
+		).Scan(&userId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidTransition
+			}
+			return fmt.Errorf("HideQuote error: %w", err)
+		}
+		if err := resubmitCurrent(ctx, tx, quoteId, userId); err != nil {
+			return fmt.Errorf("HideQuote revision: %w", err)
+		}
+		return nil
+	})
+}
+
+// ResolveReports closes every open report on a quote and returns how many
+// there were.
+func (r *SQLiteQuoteRepository) ResolveReports(ctx context.Context, quoteId string, status ReportStatus, resolvedBy string) (int, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE quote_reports SET status = $1, resolved_by = $2, resolved_at = $3 WHERE quote_id = $4 AND status = 'open'",
+		status, resolvedBy, time.Now(), quoteId,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ResolveReports error: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// GetReportSummaries returns up to limit quotes with open reports, most
+// reported first.
+func (r *SQLiteQuoteRepository) GetReportSummaries(ctx context.Context, limit int) ([]*ReportSummary, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT q.id, q.quote, q.status, q.hidden_at, r.reason, r.created_at
+		FROM quote_reports r JOIN quotes q ON q.id = r.quote_id
+		WHERE r.status = 'open' AND q.id IN (
+			SELECT quote_id FROM quote_reports WHERE status = 'open'
+			GROUP BY quote_id ORDER BY COUNT(*) DESC, MAX(created_at) DESC LIMIT $1
+		)`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetReportSummaries error: %w", err)
+	}
+	defer rows.Close()
+	byQuote := map[string]*ReportSummary{}
+	var summaries []*ReportSummary
+	for rows.Next() {
+		var id, text string
+		var status Status
+		var hiddenAt sql.NullTime
+		var reason ReportReason
+		var reportedAt time.Time
+		if err := rows.Scan(&id, &text, &status, &hiddenAt, &reason, &reportedAt); err != nil {
+			return nil, fmt.Errorf("GetReportSummaries scan: %w", err)
+		}
+		summary := byQuote[id]
+		if summary == nil {
+			summary = &ReportSummary{QuoteId: id, Quote: text, Status: status, Reasons: map[ReportReason]int{}}
+			if hiddenAt.Valid {
+				summary.HiddenAt = &hiddenAt.Time
+			}
+			byQuote[id] = summary
+			summaries = append(summaries, summary)
+		}
+		summary.OpenReports++
+		summary.Reasons[reason]++
+		if reportedAt.After(summary.LastReportedAt) {
+			summary.LastReportedAt = reportedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetReportSummaries rows: %w", err)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].OpenReports != summaries[j].OpenReports {
+			return summaries[i].OpenReports > summaries[j].OpenReports
+		}
+		return summaries[i].LastReportedAt.After(summaries[j].LastReportedAt)
+	})
+	return summaries, nil
+}
+
+// GetReports returns every report on a quote, newest first.
+func (r *SQLiteQuoteRepository) GetReports(ctx context.Context, quoteId string) ([]*Report, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, quote_id, user_id, reason, details, status, created_at, resolved_by, resolved_at
+		FROM quote_reports WHERE quote_id = $1 ORDER BY created_at DESC, id DESC`,
+		quoteId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetReports error: %w", err)
+	}
+	defer rows.Close()
+	var reports []*Report
+	for rows.Next() {
+		report := &Report{}
+		var resolvedBy sql.NullString
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&report.Id, &report.QuoteId, &report.UserId, &report.Reason, &report.Details, &report.Status, &report.CreatedAt, &resolvedBy, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("GetReports scan: %w", err)
+		}
+		report.ResolvedBy = resolvedBy.String
+		if resolvedAt.Valid {
+			report.ResolvedAt = &resolvedAt.Time
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetReports rows: %w", err)
+	}
+	return reports, nil
+}
+
+// SetLike records that a user likes a quote, or no longer does, and returns
+// the quote's like count. Liking twice or unliking a quote that was never
+// liked changes nothing.
+func (r *SQLiteQuoteRepository) SetLike(ctx context.Context, quoteId, userId string, liked bool) (int, error) {
+	var count int
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var result sql.Result
+		var err error
+		delta := 1
+		if liked {
+			result, err = tx.ExecContext(ctx,
+				"INSERT INTO quote_likes (quote_id, user_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+				quoteId, userId, time.Now(),
+			)
+		} else {
+			delta = -1
+			result, err = tx.ExecContext(ctx, "DELETE FROM quote_likes WHERE quote_id = $1 AND user_id = $2", quoteId, userId)
+		}
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			delta = 0
+		}
+		return tx.QueryRowContext(ctx,
+			"UPDATE quotes SET like_count = MAX(like_count + $1, 0) WHERE id = $2 RETURNING like_count",
+			delta, quoteId,
+		).Scan(&count)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrQuoteNotFound
+		}
+		return 0, fmt.Errorf("SetLike error: %w", err)
+	}
+	return count, nil
+}
+
+// ReconcileLikeCounts recounts the likes of every quote whose counter has
+// drifted from quote_likes, for instance after likes were removed by hand,
+// and returns how many it fixed.
+func (r *SQLiteQuoteRepository) ReconcileLikeCounts(ctx context.Context) (int, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE quotes SET like_count = (SELECT COUNT(*) FROM quote_likes l WHERE l.quote_id = quotes.id)
+		WHERE like_count != (SELECT COUNT(*) FROM quote_likes l WHERE l.quote_id = quotes.id)`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("ReconcileLikeCounts error: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
 }
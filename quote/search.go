@@ -0,0 +1,24 @@
+package quote
+
+import (
+	"strings"
+	"unicode"
+)
+
+// matchExpression turns free text from a search box into an FTS5 match
+// expression: every word must appear, as a whole word or the start of one.
+// Lowercasing keeps AND, OR and NOT from acting as operators and everything
+// but letters and digits is dropped, so FTS syntax in the input is never
+// interpreted. It returns "" if there are no words.
+func matchExpression(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	for i := range words {
+		words[i] += "*"
+	}
+	return strings.Join(words, " ")
+}
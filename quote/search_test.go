@@ -0,0 +1,20 @@
+package quote
+
+import "testing"
+
+func TestMatchExpression(t *testing.T) {
+	testCases := []struct {
+		q, want string
+	}{
+		{"simple", "simple*"},
+		{"  Keep it SIMPLE ", "keep* it* simple*"},
+		{`"quoted" NEAR/2 (x OR y) -z`, "quoted* near* 2* x* or* y* z*"},
+		{"café", "café*"},
+		{"*** --", ""},
+	}
+	for _, tc := range testCases {
+		if got := matchExpression(tc.q); got != tc.want {
+			t.Errorf("matchExpression(%q) = %q, want %q", tc.q, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package quote
+
+// Status is where a quote is in moderation.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusPending   Status = "pending"
+	StatusApproved  Status = "approved"
+	StatusRejected  Status = "rejected"
+	StatusWithdrawn Status = "withdrawn"
+
+	// StatusSuperseded only applies to revisions: the edit was replaced by a
+	// newer one, or its quote was withdrawn, before anyone reviewed it.
+	StatusSuperseded Status = "superseded"
+)
+
+// transitions lists the statuses each status may move to.
+//
+//	draft ──submit──▶ pending ──approve──▶ approved
+//	                     │
+//	                     └──reject──▶ rejected ──edit/submit──▶ pending
+//
+// Edits to an approved quote wait as a pending revision while the quote stays
+// published, so approved never goes back to pending here; only reports hide
+// it again. Authors can withdraw a quote at any point after it was submitted
+// and submit it again later.
+var transitions = map[Status][]Status{
+	StatusDraft:     {StatusPending},
+	StatusPending:   {StatusApproved, StatusRejected, StatusWithdrawn},
+	StatusApproved:  {StatusWithdrawn},
+	StatusRejected:  {StatusPending, StatusWithdrawn},
+	StatusWithdrawn: {StatusPending},
+}
+
+// CanTransition reports whether a quote in status s may move to next.
+func (s Status) CanTransition(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether s is a status a quote can be in.
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
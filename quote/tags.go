@@ -0,0 +1,80 @@
+package quote
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTags       = 10
+	maxTagLength  = 32
+	tagsSeparator = ","
+)
+
+// Slugify normalizes a tag: lowercase letters and digits, with every other
+// run of characters turned into a single hyphen. It returns "" if nothing is
+// left.
+func Slugify(tag string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		hyphen = true
+	}
+	slug := b.String()
+	if len(slug) > maxTagLength {
+		slug = strings.TrimRight(truncate(slug, maxTagLength), "-")
+	}
+	return slug
+}
+
+// normalizeTags slugifies tags and drops duplicates, keeping the first
+// occurrence order.
+func normalizeTags(tags []string) ([]string, error) {
+	slugs := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		slug := Slugify(tag)
+		if slug == "" {
+			return nil, ErrInvalidTag
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > maxTags {
+		return nil, ErrTooManyTags
+	}
+	return slugs, nil
+}
+
+func joinTags(slugs []string) string {
+	return strings.Join(slugs, tagsSeparator)
+}
+
+func splitTags(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, tagsSeparator)
+}
+
+// truncate cuts s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,21 @@
+package quote
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	testCases := []struct {
+		tag, want string
+	}{
+		{"Design", "design"},
+		{"  Software   Engineering ", "software-engineering"},
+		{"C++ / Go!", "c-go"},
+		{"Café", "café"},
+		{"---", ""},
+		{"a-very-long-tag-that-goes-on-and-on-forever", "a-very-long-tag-that-goes-on-and"},
+	}
+	for _, tc := range testCases {
+		if got := Slugify(tc.tag); got != tc.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tc.tag, got, tc.want)
+		}
+	}
+}
@@ -1,46 +1,127 @@
 package quote
 
 import (
+	"context"
 	"errors"
 	"log"
+	"slices"
+	"time"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/automod"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/policy"
 )
 
 type QuoteService interface {
-	CreateQuote(userId string, quote string) error
-	UpdateQuote(userId string, role string, quoteId string, quote string) error
-	DeleteQuote(userId string, role string, quoteId string) error
-	GetQuotes(role string) ([]*Quote, error)
-	GetQuotesByUserId(userId string, role string, requestedUserId string) ([]*Quote, error)
-	ApproveQuote(userId string, role string, quoteId string) error
-	GetUnapprovedQuotes() ([]*Quote, error)
+	CreateQuote(ctx context.Context, a actor.Actor, quote string, tags []string, attribution *AttributionRequest, draft bool) error
+	UpdateQuote(ctx context.Context, a actor.Actor, quoteId string, quote string, tags []string, attribution *AttributionRequest) error
+	DeleteQuote(ctx context.Context, a actor.Actor, quoteId string) error
+	GetQuotes(ctx context.Context, a actor.Actor, f QuoteFilter, p page.Params) ([]*Quote, string, error)
+	GetQuotesByUserId(ctx context.Context, a actor.Actor, requestedUserId string, f QuoteFilter, p page.Params) ([]*Quote, string, error)
+	GetPublishedQuotesByUserId(ctx context.Context, userId string, p page.Params) ([]*Quote, string, error)
+	ApproveQuote(ctx context.Context, a actor.Actor, quoteId string) error
+	RejectQuote(ctx context.Context, a actor.Actor, quoteId string, reason string) error
+	SubmitQuote(ctx context.Context, a actor.Actor, quoteId string) error
+	WithdrawQuote(ctx context.Context, a actor.Actor, quoteId string) error
+	GetRevisions(ctx context.Context, a actor.Actor, quoteId string) ([]*Revision, error)
+	DiffRevisions(ctx context.Context, a actor.Actor, quoteId string, from, to int) (*QuoteDiff, error)
+	RollbackQuote(ctx context.Context, a actor.Actor, quoteId string, revision int) error
+	GetUnapprovedQuotes(ctx context.Context, a actor.Actor, f QuoteFilter, p page.Params) ([]*Quote, string, error)
+	SearchQuotes(ctx context.Context, a actor.Actor, q string, limit int) ([]*SearchResult, error)
+	GetTagCounts(ctx context.Context, a actor.Actor, limit int) ([]*TagCount, error)
+	RenameTag(ctx context.Context, a actor.Actor, from, to string) error
+	MergeTags(ctx context.Context, a actor.Actor, from, into string) error
+	GetAuthors(ctx context.Context, a actor.Actor, p page.Params) ([]*Author, string, error)
+	GetAuthor(ctx context.Context, a actor.Actor, authorId string) (*Author, []*Source, error)
+	MergeAuthors(ctx context.Context, a actor.Actor, from, into string) error
+	ReportQuote(ctx context.Context, a actor.Actor, quoteId string, reason ReportReason, details string) error
+	GetReportSummaries(ctx context.Context, a actor.Actor, limit int) ([]*ReportSummary, error)
+	GetReports(ctx context.Context, a actor.Actor, quoteId string) ([]*Report, error)
+	ResolveReports(ctx context.Context, a actor.Actor, quoteId, action, reason string) error
+	LikeQuote(ctx context.Context, a actor.Actor, quoteId string) (int, error)
+	UnlikeQuote(ctx context.Context, a actor.Actor, quoteId string) (int, error)
+	GetLikedQuotes(ctx context.Context, a actor.Actor, p page.Params) ([]*Quote, string, error)
+}
+
+type QuoteServiceImpl struct {
+	repo QuoteRepository
+	mod  *automod.Pipeline
 }
 
-type QuoteServiceImpl struct{}
+func NewQuoteService(repo QuoteRepository, mod *automod.Pipeline) *QuoteServiceImpl {
+	return &QuoteServiceImpl{repo: repo, mod: mod}
+}
 
-func (s *QuoteServiceImpl) CreateQuote(userId string, quote string) error {
-	if userId == "" || quote == "" {
+// CreateQuote adds a quote to the moderation queue, or saves it as a draft.
+// It returns a *DuplicateError if the same or nearly the same quote has
+// already been submitted, and an *AutoRejectedError if automatic moderation
+// refuses it. Clean quotes from trusted authors are approved straight away.
+func (s *QuoteServiceImpl) CreateQuote(ctx context.Context, a actor.Actor, quote string, tags []string, attribution *AttributionRequest, draft bool) error {
+	if a.UID == "" || quote == "" {
 		log.Println("Error: Invalid request body")
 		return ErrInvalidRequestBody
 	}
-	err := createQuote(&Quote{
-		UserId:   userId,
-		Quote:    quote,
-		Approved: false,
-	})
+	slugs, err := normalizeTags(tags)
+	if err != nil {
+		log.Println("Error: Invalid tags:", err)
+		return err
+	}
+	if !policy.Can(a, policy.QuoteCreate, policy.OwnedBy(a.UID)) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	duplicates, err := s.repo.FindDuplicates(ctx, fingerprint(quote), "", DuplicateThreshold)
+	if err != nil {
+		log.Println("Error checking for duplicates:", err)
+		return ErrCreateQuote
+	}
+	if len(duplicates) > 0 {
+		log.Printf("Error: quote duplicates %s", duplicates[0].QuoteId)
+		return &DuplicateError{Duplicate: *duplicates[0]}
+	}
+	verdict, err := s.screen(ctx, a, quote)
+	if err != nil {
+		return err
+	}
+	at, err := s.resolveAttribution(ctx, attribution, Attribution{})
+	if err != nil {
+		if errors.Is(err, ErrInvalidAttribution) {
+			return err
+		}
+		return ErrCreateQuote
+	}
+	status := StatusPending
+	if draft {
+		status = StatusDraft
+	}
+	created := &Quote{
+		UserId:      a.UID,
+		Quote:       quote,
+		Tags:        slugs,
+		Attribution: at,
+		Status:      status,
+	}
+	err = s.repo.CreateQuote(ctx, created)
 	if err != nil {
 		log.Println("Error creating quote:", err)
 		return ErrCreateQuote
 	}
+	s.settle(ctx, a, created.Id, verdict, !draft)
 	return nil
 }
 
-func (s *QuoteServiceImpl) UpdateQuote(userId string, role string, quoteId string, quote string) error {
-	if userId == "" || quoteId == "" || role == "" || quote == "" {
+// UpdateQuote changes the text, tags and attribution of a quote; nil tags or
+// attribution keep the current ones. Drafts stay drafts. Edits to an approved quote wait for review while
+// the approved text stays live; anything else goes back to the moderation
+// queue. The new text is screened like a new quote.
+func (s *QuoteServiceImpl) UpdateQuote(ctx context.Context, a actor.Actor, quoteId string, quote string, tags []string, attribution *AttributionRequest) error {
+	if a.UID == "" || quoteId == "" || quote == "" {
 		log.Println("Error: Invalid request body")
 		return ErrInvalidRequestBody
 	}
 
-	quoteGotten, err := getQuoteById(quoteId)
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
 	if err != nil {
 		if errors.Is(err, ErrQuoteNotFound) {
 			log.Println("Error: Quote not found")
@@ -50,34 +131,136 @@ func (s *QuoteServiceImpl) UpdateQuote(userId string, role string, quoteId strin
 		return ErrUpdateQuote
 	}
 
-	if role != "admin" {
-		if userId != quoteGotten.UserId {
-			log.Println("Error: Not authorized")
-			return ErrNotAuthorized
+	if !policy.Can(a, policy.QuoteUpdate, policy.OwnedBy(quoteGotten.UserId)) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+
+	verdict, err := s.screen(ctx, a, quote)
+	if err != nil {
+		return err
+	}
+
+	slugs := quoteGotten.Tags
+	if tags != nil {
+		if slugs, err = normalizeTags(tags); err != nil {
+			log.Println("Error: Invalid tags:", err)
+			return err
 		}
 	}
+	at, err := s.resolveAttribution(ctx, attribution, quoteGotten.Attribution)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAttribution) {
+			return err
+		}
+		return ErrUpdateQuote
+	}
 
-	err = updateQuote(&Quote{
-		Id:       quoteId,
-		Quote:    quote,
-		Approved: false,
-	})
+	if quoteGotten.Status == StatusApproved {
+		err := s.repo.ProposeEdit(ctx, &Revision{
+			QuoteId:     quoteId,
+			Quote:       quote,
+			Tags:        slugs,
+			Attribution: at,
+			EditedBy:    a.UID,
+		})
+		if err != nil {
+			log.Println("Error updating quote:", err)
+			return ErrUpdateQuote
+		}
+		s.settle(ctx, a, quoteId, verdict, true)
+		return nil
+	}
+
+	status := StatusPending
+	if quoteGotten.Status == StatusDraft {
+		status = StatusDraft
+	} else if quoteGotten.Status != StatusPending && !quoteGotten.Status.CanTransition(StatusPending) {
+		log.Printf("Error: quote %s cannot move from %s to %s", quoteId, quoteGotten.Status, status)
+		return ErrInvalidTransition
+	}
+
+	err = s.repo.UpdateQuote(ctx, &Quote{
+		Id:          quoteId,
+		Quote:       quote,
+		Tags:        slugs,
+		Attribution: at,
+		Status:      status,
+	}, quoteGotten.Status, a.UID)
 
 	if err != nil {
 		log.Println("Error updating quote:", err)
+		if errors.Is(err, ErrInvalidTransition) {
+			return ErrInvalidTransition
+		}
 		return ErrUpdateQuote
 	}
 
+	// A quote hidden by reports waits for a moderator whoever edits it.
+	s.settle(ctx, a, quoteId, verdict, status == StatusPending && quoteGotten.HiddenAt == nil)
 	return nil
 }
 
-func (s *QuoteServiceImpl) DeleteQuote(userId string, role string, quoteId string) error {
-	if userId == "" || quoteId == "" || role == "" {
+// screen runs automatic moderation over text submitted by a. It returns an
+// *AutoRejectedError if the text is refused.
+func (s *QuoteServiceImpl) screen(ctx context.Context, a actor.Actor, text string) (automod.Result, error) {
+	verdict := s.mod.Moderate(ctx, automod.Submission{UserId: a.UID, Text: text})
+	if verdict.Decision == automod.Reject {
+		log.Printf("Error: quote from %s rejected by automod: %v", a.UID, verdict.Reasons)
+		return verdict, &AutoRejectedError{Reasons: verdict.Reasons}
+	}
+	return verdict, nil
+}
+
+// settle stores the automod verdict on a saved quote and, when the quote is
+// waiting for review, the verdict is clean and a is trusted, approves it.
+// The quote is already saved, so failures are logged and it is left for a
+// moderator.
+func (s *QuoteServiceImpl) settle(ctx context.Context, a actor.Actor, quoteId string, verdict automod.Result, inReview bool) {
+	if err := s.repo.SetAutomod(ctx, quoteId, verdict); err != nil {
+		log.Println("Error storing automod result:", err)
+		return
+	}
+	if !inReview || verdict.Decision != automod.Allow {
+		return
+	}
+	trusted, err := s.trusted(ctx, a)
+	if err != nil {
+		log.Println("Error checking author trust:", err)
+		return
+	}
+	if !trusted {
+		return
+	}
+	if err := s.review(ctx, actor.Actor{UID: automod.ReviewerId}, quoteId, StatusApproved, ""); err != nil {
+		log.Println("Error auto-approving quote:", err)
+	}
+}
+
+// trusted reports whether a's clean submissions skip the moderation queue:
+// moderators always do, and authors do once their record meets the
+// pipeline's bar.
+func (s *QuoteServiceImpl) trusted(ctx context.Context, a actor.Actor) (bool, error) {
+	if policy.Can(a, policy.QuoteApprove, policy.Any) {
+		return true, nil
+	}
+	if s.mod.TrustedAfter <= 0 {
+		return false, nil
+	}
+	counts, err := s.repo.CountQuotesByStatus(ctx, a.UID)
+	if err != nil {
+		return false, err
+	}
+	return s.mod.Trusted(counts[StatusApproved], counts[StatusRejected]), nil
+}
+
+func (s *QuoteServiceImpl) DeleteQuote(ctx context.Context, a actor.Actor, quoteId string) error {
+	if a.UID == "" || quoteId == "" {
 		log.Println("Error: Invalid request body")
 		return ErrInvalidRequestBody
 	}
 
-	quoteGotten, err := getQuoteById(quoteId)
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
 	if err != nil {
 		if errors.Is(err, ErrQuoteNotFound) {
 			log.Println("Error: Quote not found")
@@ -87,14 +270,12 @@ func (s *QuoteServiceImpl) DeleteQuote(userId string, role string, quoteId strin
 		return ErrDeletingQuote
 	}
 
-	if role != "admin" {
-		if userId != quoteGotten.UserId {
-			log.Println("Error: Not authorized")
-			return ErrNotAuthorized
-		}
+	if !policy.Can(a, policy.QuoteDelete, policy.OwnedBy(quoteGotten.UserId)) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
 	}
 
-	err = deleteQuote(quoteId)
+	err = s.repo.DeleteQuote(ctx, quoteId)
 	if err != nil {
 		log.Println("Error deleting quote:", err)
 		return ErrDeletingQuote
@@ -103,88 +284,828 @@ func (s *QuoteServiceImpl) DeleteQuote(userId string, role string, quoteId strin
 	return nil
 }
 
-func (s *QuoteServiceImpl) GetQuotes(role string) ([]*Quote, error) {
-	var quotes []*Quote
-	var err error
+// GetQuotes returns one page of quotes and the cursor for the next page.
+// Users who cannot read every quote, and anonymous callers, only get approved
+// ones.
+func (s *QuoteServiceImpl) GetQuotes(ctx context.Context, a actor.Actor, f QuoteFilter, p page.Params) ([]*Quote, string, error) {
+	readAll := policy.Can(a, policy.QuoteRead, policy.Any)
+	if !readAll {
+		if f.Status != "" && f.Status != StatusApproved {
+			log.Println("Error: Not authorized")
+			return nil, "", ErrNotAuthorized
+		}
+		f.Status = StatusApproved
+	}
+	f.PendingEdits = false
+
+	quotes, err := s.repo.ListQuotes(ctx, f, p)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: No quotes found")
+			return nil, "", ErrQuoteNotFound
+		}
+		log.Println("Error getting quotes:", err)
+		return nil, "", ErrGettingQuote
+	}
+	if !readAll {
+		hideReview(quotes)
+	}
+
+	quotes, next := page.Trim(quotes, p, quoteCursor)
+	return quotes, next, nil
+}
 
-	if role == "admin" {
-		quotes, err = getAllQuotes()
+// GetQuotesByUserId returns one page of a user's quotes. Authors see every
+// quote with its status, any rejection reason and any pending edit; everyone
+// else, including anonymous callers, only sees approved quotes.
+func (s *QuoteServiceImpl) GetQuotesByUserId(ctx context.Context, a actor.Actor, requestedUserId string, f QuoteFilter, p page.Params) ([]*Quote, string, error) {
+	if requestedUserId == "" {
+		log.Println("Error: Invalid request body")
+		return nil, "", ErrInvalidRequestBody
+	}
+
+	f.UserId = requestedUserId
+	owner := policy.Can(a, policy.QuoteRead, policy.OwnedBy(requestedUserId))
+	if owner {
+		f.PendingEdits = true
 	} else {
-		quotes, err = getAllApprovedQuotes()
+		if f.Status != "" && f.Status != StatusApproved {
+			log.Println("Error: Not authorized")
+			return nil, "", ErrNotAuthorized
+		}
+		f.Status = StatusApproved
+		f.PendingEdits = false
 	}
 
+	quotes, err := s.repo.ListQuotes(ctx, f, p)
 	if err != nil {
 		if errors.Is(err, ErrQuoteNotFound) {
 			log.Println("Error: No quotes found")
-			return nil, ErrQuoteNotFound
+			return nil, "", ErrQuoteNotFound
 		}
 		log.Println("Error getting quotes:", err)
-		return nil, err
+		return nil, "", ErrGettingQuote
+	}
+	if !owner {
+		hideReview(quotes)
 	}
 
-	return quotes, nil
+	quotes, next := page.Trim(quotes, p, quoteCursor)
+	return quotes, next, nil
 }
 
-func (s *QuoteServiceImpl) GetQuotesByUserId(userId string, role string, requestedUserId string) ([]*Quote, error) {
-	if userId == "" || requestedUserId == "" {
+// GetPublishedQuotesByUserId returns one page of a user's approved quotes for
+// anyone, signed in or not. A user without any gets an empty page.
+func (s *QuoteServiceImpl) GetPublishedQuotesByUserId(ctx context.Context, userId string, p page.Params) ([]*Quote, string, error) {
+	if userId == "" {
 		log.Println("Error: Invalid request body")
-		return nil, ErrInvalidRequestBody
+		return nil, "", ErrInvalidRequestBody
+	}
+
+	quotes, err := s.repo.ListQuotes(ctx, QuoteFilter{UserId: userId, Status: StatusApproved}, p)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			return []*Quote{}, "", nil
+		}
+		log.Println("Error getting quotes:", err)
+		return nil, "", ErrGettingQuote
 	}
+	hideReview(quotes)
 
-	var quotes []*Quote
-	var err error
+	quotes, next := page.Trim(quotes, p, quoteCursor)
+	return quotes, next, nil
+}
 
-	if role == "admin" || userId == requestedUserId {
-		quotes, err = getQuotesByUserId(requestedUserId)
-	} else {
-		quotes, err = getApprovedQuotesByUserId(requestedUserId)
+// SearchQuotes finds quotes by their text, best match first. Visibility
+// follows GetQuotes, except that users also find their own quotes.
+func (s *QuoteServiceImpl) SearchQuotes(ctx context.Context, a actor.Actor, q string, limit int) ([]*SearchResult, error) {
+	match := matchExpression(q)
+	if match == "" {
+		log.Println("Error: empty search")
+		return nil, ErrInvalidRequestBody
 	}
 
+	readAll := policy.Can(a, policy.QuoteRead, policy.Any)
+	results, err := s.repo.SearchQuotes(ctx, match, a.UID, readAll, limit)
 	if err != nil {
 		if errors.Is(err, ErrQuoteNotFound) {
-			log.Println("Error: No quotes found")
 			return nil, ErrQuoteNotFound
 		}
-		log.Println("Error getting quotes:", err)
+		log.Println("Error searching quotes:", err)
 		return nil, ErrGettingQuote
 	}
+	if !readAll {
+		for _, r := range results {
+			if r.UserId != a.UID {
+				r.ReviewedBy = ""
+				r.RejectionReason = ""
+			}
+		}
+	}
+	return results, nil
+}
+
+// GetTagCounts returns how many approved quotes carry each tag, most used
+// first.
+func (s *QuoteServiceImpl) GetTagCounts(ctx context.Context, a actor.Actor, limit int) ([]*TagCount, error) {
+	counts, err := s.repo.GetTagCounts(ctx, limit)
+	if err != nil {
+		log.Println("Error getting tags:", err)
+		return nil, ErrGettingTags
+	}
+	return counts, nil
+}
+
+// RenameTag changes a tag's slug everywhere it is used.
+func (s *QuoteServiceImpl) RenameTag(ctx context.Context, a actor.Actor, from, to string) error {
+	if !policy.Can(a, policy.TagManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	from, to = Slugify(from), Slugify(to)
+	if from == "" || to == "" {
+		log.Println("Error: Invalid tag")
+		return ErrInvalidTag
+	}
+	if from == to {
+		return nil
+	}
+	return s.updateTags(s.repo.RenameTag(ctx, from, to))
+}
+
+// MergeTags retags every quote tagged from with into and deletes from.
+func (s *QuoteServiceImpl) MergeTags(ctx context.Context, a actor.Actor, from, into string) error {
+	if !policy.Can(a, policy.TagManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	from, into = Slugify(from), Slugify(into)
+	if from == "" || into == "" || from == into {
+		log.Println("Error: Invalid tag")
+		return ErrInvalidTag
+	}
+	return s.updateTags(s.repo.MergeTags(ctx, from, into))
+}
+
+func (s *QuoteServiceImpl) updateTags(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrTagNotFound), errors.Is(err, ErrTagExists):
+		return err
+	default:
+		log.Println("Error updating tag:", err)
+		return ErrUpdatingTag
+	}
+}
+
+// ApproveQuote publishes a pending quote, or the pending edit of an approved one.
+func (s *QuoteServiceImpl) ApproveQuote(ctx context.Context, a actor.Actor, quoteId string) error {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+
+	if !policy.Can(a, policy.QuoteApprove, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
 
-	return quotes, nil
+	err := s.review(ctx, a, quoteId, StatusApproved, "")
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) || errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
+		log.Println("Error approving quote:", err)
+		return ErrApprovingQuote
+	}
+
+	return nil
 }
 
-func (s *QuoteServiceImpl) ApproveQuote(userId string, role string, quoteId string) error {
-	if userId == "" || quoteId == "" || role == "" {
+// RejectQuote turns down a pending quote, or the pending edit of an approved one,
+// which then keeps its current text. The reason is shown to the author.
+func (s *QuoteServiceImpl) RejectQuote(ctx context.Context, a actor.Actor, quoteId string, reason string) error {
+	if a.UID == "" || quoteId == "" || reason == "" {
 		log.Println("Error: Invalid request body")
 		return ErrInvalidRequestBody
 	}
 
-	if role != "admin" {
+	if !policy.Can(a, policy.QuoteApprove, policy.Any) {
 		log.Println("Error: Not authorized")
 		return ErrNotAuthorized
 	}
 
-	err := approveQuote(quoteId)
+	err := s.review(ctx, a, quoteId, StatusRejected, reason)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) || errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
+		log.Println("Error rejecting quote:", err)
+		return ErrRejectingQuote
+	}
+
+	return nil
+}
+
+// SubmitQuote sends a draft, withdrawn or rejected quote to the moderation queue.
+func (s *QuoteServiceImpl) SubmitQuote(ctx context.Context, a actor.Actor, quoteId string) error {
+	return s.authorTransition(ctx, a, quoteId, StatusPending)
+}
+
+// WithdrawQuote takes a submitted quote out of moderation and off public listings.
+func (s *QuoteServiceImpl) WithdrawQuote(ctx context.Context, a actor.Actor, quoteId string) error {
+	return s.authorTransition(ctx, a, quoteId, StatusWithdrawn)
+}
+
+// review records a moderator's decision on a quote, or on the pending edit of
+// an approved quote.
+func (s *QuoteServiceImpl) review(ctx context.Context, a actor.Actor, quoteId string, next Status, reason string) error {
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
 	if err != nil {
 		if errors.Is(err, ErrQuoteNotFound) {
 			log.Println("Error: Quote not found")
 			return ErrQuoteNotFound
 		}
-		log.Println("Error approving quote:", err)
-		return ErrApprovingQuote
+		return err
 	}
 
+	if quoteGotten.Status == StatusApproved {
+		edit, err := s.repo.GetPendingEdit(ctx, quoteId)
+		if err != nil {
+			if errors.Is(err, ErrRevisionNotFound) {
+				log.Printf("Error: quote %s is approved and has no pending edit", quoteId)
+				return ErrInvalidTransition
+			}
+			return err
+		}
+		now := time.Now()
+		edit.Status = next
+		edit.ReviewedBy = a.UID
+		edit.ReviewedAt = &now
+		edit.RejectionReason = reason
+		if err := s.repo.ReviewEdit(ctx, edit); err != nil {
+			return err
+		}
+		s.learn(ctx, a, quoteGotten.UserId, edit.Quote, next)
+		return nil
+	}
+
+	if !quoteGotten.Status.CanTransition(next) {
+		log.Printf("Error: quote %s cannot move from %s to %s", quoteId, quoteGotten.Status, next)
+		return ErrInvalidTransition
+	}
+
+	now := time.Now()
+	err = s.repo.UpdateStatus(ctx, &Quote{
+		Id:              quoteId,
+		Status:          next,
+		ReviewedBy:      a.UID,
+		ReviewedAt:      &now,
+		RejectionReason: reason,
+	}, quoteGotten.Status)
+	if err != nil {
+		return err
+	}
+	s.learn(ctx, a, quoteGotten.UserId, quoteGotten.Quote, next)
+	if quoteGotten.HiddenAt != nil && (next == StatusApproved || next == StatusRejected) {
+		// Deciding on a quote hidden by reports settles them.
+		outcome := ReportDismissed
+		if next == StatusRejected {
+			outcome = ReportUpheld
+		}
+		if _, err := s.repo.ResolveReports(ctx, quoteId, outcome, a.UID); err != nil {
+			log.Println("Error resolving reports:", err)
+		}
+	}
 	return nil
 }
 
-func (s *QuoteServiceImpl) GetUnapprovedQuotes() ([]*Quote, error) {
-	unapprovedQuotes, err := getUnapprovedQuotes()
+// learn passes a moderator's decision on a text to the automod pipeline so
+// its classifiers can learn from it. Automod's own approvals are not learned
+// from, or it would only learn to agree with itself.
+func (s *QuoteServiceImpl) learn(ctx context.Context, a actor.Actor, authorId, text string, decision Status) {
+	if a.UID == automod.ReviewerId || (decision != StatusApproved && decision != StatusRejected) {
+		return
+	}
+	if err := s.mod.Learn(ctx, automod.Submission{UserId: authorId, Text: text}, decision == StatusRejected); err != nil {
+		log.Println("Error training automod:", err)
+	}
+}
+
+// authorTransition moves a quote on behalf of its author, keeping the last review.
+func (s *QuoteServiceImpl) authorTransition(ctx context.Context, a actor.Actor, quoteId string, next Status) error {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return ErrQuoteNotFound
+		}
+		log.Println("Error updating quote:", err)
+		return ErrUpdateQuote
+	}
+
+	if !policy.Can(a, policy.QuoteUpdate, policy.OwnedBy(quoteGotten.UserId)) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+
+	if !quoteGotten.Status.CanTransition(next) {
+		log.Printf("Error: quote %s cannot move from %s to %s", quoteId, quoteGotten.Status, next)
+		return ErrInvalidTransition
+	}
+
+	from := quoteGotten.Status
+	quoteGotten.Status = next
+	if err := s.repo.UpdateStatus(ctx, quoteGotten, from); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return ErrInvalidTransition
+		}
+		log.Println("Error updating quote:", err)
+		return ErrUpdateQuote
+	}
+	return nil
+}
+
+// GetUnapprovedQuotes returns one page of the moderation queue.
+func (s *QuoteServiceImpl) GetUnapprovedQuotes(ctx context.Context, a actor.Actor, f QuoteFilter, p page.Params) ([]*Quote, string, error) {
+	if !policy.Can(a, policy.QuoteApprove, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, "", ErrNotAuthorized
+	}
+
+	unapprovedQuotes, err := s.repo.GetUnapprovedQuotes(ctx, f, p)
 	if err != nil {
 		if errors.Is(err, ErrQuoteNotFound) {
 			log.Println("Error: No unapproved quotes found")
-			return nil, ErrQuoteNotFound
+			return nil, "", ErrQuoteNotFound
 		}
 		log.Println("Error getting unapproved quotes:", err)
-		return nil, ErrGettingQuote
+		return nil, "", ErrGettingQuote
+	}
+
+	unapprovedQuotes, next := page.Trim(unapprovedQuotes, p, quoteCursor)
+	s.hintDuplicates(ctx, unapprovedQuotes)
+	return unapprovedQuotes, next, nil
+}
+
+// hintDuplicates points moderators at the quote most similar to the text
+// each queued quote is waiting to publish.
+func (s *QuoteServiceImpl) hintDuplicates(ctx context.Context, quotes []*Quote) {
+	for _, q := range quotes {
+		text := q.Quote
+		if q.PendingEdit != nil {
+			text = q.PendingEdit.Quote
+		}
+		duplicates, err := s.repo.FindDuplicates(ctx, fingerprint(text), q.Id, HintThreshold)
+		if err != nil {
+			log.Printf("Error checking quote %s for duplicates: %v", q.Id, err)
+			continue
+		}
+		if len(duplicates) > 0 {
+			q.PossibleDuplicateOf = duplicates[0]
+		}
+	}
+}
+
+// GetRevisions lists every version of a quote. The history is open to the
+// quote's author, to moderators and to anyone who can read every quote.
+func (s *QuoteServiceImpl) GetRevisions(ctx context.Context, a actor.Actor, quoteId string) ([]*Revision, error) {
+	if err := s.authorizeHistory(ctx, a, quoteId); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.GetRevisions(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrRevisionNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		log.Println("Error getting revisions:", err)
+		return nil, ErrGettingRevisions
+	}
+	return revisions, nil
+}
+
+// DiffRevisions compares two revisions of a quote word by word.
+func (s *QuoteServiceImpl) DiffRevisions(ctx context.Context, a actor.Actor, quoteId string, from, to int) (*QuoteDiff, error) {
+	if err := s.authorizeHistory(ctx, a, quoteId); err != nil {
+		return nil, err
+	}
+
+	revs := make([]*Revision, 2)
+	for i, n := range []int{from, to} {
+		rev, err := s.repo.GetRevision(ctx, quoteId, n)
+		if err != nil {
+			if errors.Is(err, ErrRevisionNotFound) {
+				log.Printf("Error: revision %d of quote %s not found", n, quoteId)
+				return nil, ErrRevisionNotFound
+			}
+			log.Println("Error getting revision:", err)
+			return nil, ErrGettingRevisions
+		}
+		revs[i] = rev
+	}
+
+	return &QuoteDiff{
+		QuoteId: quoteId,
+		From:    from,
+		To:      to,
+		Chunks:  WordDiff(revs[0].Quote, revs[1].Quote),
+	}, nil
+}
+
+// RollbackQuote restores the text of an earlier approved revision and publishes
+// it. Drafts and withdrawn quotes are left to their authors.
+func (s *QuoteServiceImpl) RollbackQuote(ctx context.Context, a actor.Actor, quoteId string, revision int) error {
+	if a.UID == "" || quoteId == "" || revision < 1 {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+
+	if !policy.Can(a, policy.QuoteUpdate, policy.Any) || !policy.Can(a, policy.QuoteApprove, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return ErrQuoteNotFound
+		}
+		log.Println("Error rolling back quote:", err)
+		return ErrRollbackQuote
+	}
+	if quoteGotten.Status == StatusDraft || quoteGotten.Status == StatusWithdrawn {
+		log.Printf("Error: quote %s is %s and cannot be rolled back", quoteId, quoteGotten.Status)
+		return ErrInvalidTransition
+	}
+
+	rev, err := s.repo.GetRevision(ctx, quoteId, revision)
+	if err != nil {
+		if errors.Is(err, ErrRevisionNotFound) {
+			return ErrRevisionNotFound
+		}
+		log.Println("Error rolling back quote:", err)
+		return ErrRollbackQuote
+	}
+	if rev.Status != StatusApproved {
+		log.Printf("Error: revision %d of quote %s is %s", revision, quoteId, rev.Status)
+		return ErrRevisionNotApproved
+	}
+
+	if err := s.repo.RestoreRevision(ctx, rev, quoteGotten.Status, a.UID); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return ErrInvalidTransition
+		}
+		log.Println("Error rolling back quote:", err)
+		return ErrRollbackQuote
+	}
+	if quoteGotten.HiddenAt != nil {
+		// Rolling back replaces the reported text, which upholds the reports.
+		if _, err := s.repo.ResolveReports(ctx, quoteId, ReportUpheld, a.UID); err != nil {
+			log.Println("Error resolving reports:", err)
+		}
+	}
+	return nil
+}
+
+func (s *QuoteServiceImpl) authorizeHistory(ctx context.Context, a actor.Actor, quoteId string) error {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return ErrQuoteNotFound
+		}
+		log.Println("Error getting quote:", err)
+		return ErrGettingRevisions
+	}
+
+	if !policy.Can(a, policy.QuoteRead, policy.OwnedBy(quoteGotten.UserId)) && !policy.Can(a, policy.QuoteApprove, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+// hideReview drops who reviewed a quote from listings meant for other users.
+func hideReview(quotes []*Quote) {
+	for _, q := range quotes {
+		q.ReviewedBy = ""
+		q.RejectionReason = ""
+	}
+}
+
+func quoteCursor(q *Quote) (time.Time, string) {
+	return q.CreatedAt, q.Id
+}
+
+// resolveAttribution turns req into stored author and source IDs, or returns
+// current when req is nil.
+func (s *QuoteServiceImpl) resolveAttribution(ctx context.Context, req *AttributionRequest, current Attribution) (Attribution, error) {
+	if req == nil {
+		return current, nil
+	}
+	if err := normalizeAttribution(req); err != nil {
+		log.Println("Error: Invalid attribution")
+		return Attribution{}, err
+	}
+	at, err := s.repo.ResolveAttribution(ctx, req)
+	if err != nil {
+		log.Println("Error resolving attribution:", err)
+		return Attribution{}, err
+	}
+	return at, nil
+}
+
+// GetAuthors returns one page of the authors quotes are attributed to.
+func (s *QuoteServiceImpl) GetAuthors(ctx context.Context, a actor.Actor, p page.Params) ([]*Author, string, error) {
+	authors, err := s.repo.ListAuthors(ctx, p)
+	if err != nil {
+		if errors.Is(err, ErrAuthorNotFound) {
+			return nil, "", ErrAuthorNotFound
+		}
+		log.Println("Error getting authors:", err)
+		return nil, "", ErrGettingAuthors
+	}
+	authors, next := page.Trim(authors, p, func(author *Author) (time.Time, string) {
+		return author.CreatedAt, author.Id
+	})
+	return authors, next, nil
+}
+
+// GetAuthor returns an author and the works quotes of theirs come from.
+func (s *QuoteServiceImpl) GetAuthor(ctx context.Context, a actor.Actor, authorId string) (*Author, []*Source, error) {
+	author, err := s.repo.GetAuthor(ctx, authorId)
+	if err != nil {
+		if errors.Is(err, ErrAuthorNotFound) {
+			return nil, nil, ErrAuthorNotFound
+		}
+		log.Println("Error getting author:", err)
+		return nil, nil, ErrGettingAuthors
+	}
+	sources, err := s.repo.GetSources(ctx, authorId)
+	if err != nil {
+		log.Println("Error getting sources:", err)
+		return nil, nil, ErrGettingAuthors
+	}
+	return author, sources, nil
+}
+
+// MergeAuthors folds a duplicate author record into another, such as
+// "M. Twain" into "Mark Twain".
+func (s *QuoteServiceImpl) MergeAuthors(ctx context.Context, a actor.Actor, from, into string) error {
+	if !policy.Can(a, policy.AuthorManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+	if from == "" || into == "" || from == into {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+	err := s.repo.MergeAuthors(ctx, from, into)
+	if err != nil {
+		if errors.Is(err, ErrAuthorNotFound) {
+			return ErrAuthorNotFound
+		}
+		log.Println("Error merging authors:", err)
+		return ErrMergingAuthors
+	}
+	return nil
+}
+
+// ReportQuote records a reader's complaint about a published quote. Once a
+// quote has as many open reports as the automod pipeline's HideAfterReports,
+// it goes back into the moderation queue until a moderator decides.
+func (s *QuoteServiceImpl) ReportQuote(ctx context.Context, a actor.Actor, quoteId string, reason ReportReason, details string) error {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+	details, err := normalizeReport(reason, details)
+	if err != nil {
+		log.Println("Error: Invalid report:", reason)
+		return err
+	}
+	if !policy.Can(a, policy.QuoteReport, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return ErrQuoteNotFound
+		}
+		log.Println("Error reporting quote:", err)
+		return ErrReportingQuote
+	}
+	if quoteGotten.Status != StatusApproved || quoteGotten.UserId == a.UID {
+		log.Printf("Error: %s cannot report quote %s", a.UID, quoteId)
+		return ErrCannotReport
+	}
+
+	now := time.Now()
+	open, err := s.repo.CreateReport(ctx, &Report{
+		QuoteId:   quoteId,
+		UserId:    a.UID,
+		Reason:    reason,
+		Details:   details,
+		CreatedAt: now,
+	})
+	if err != nil {
+		if errors.Is(err, ErrAlreadyReported) {
+			return ErrAlreadyReported
+		}
+		log.Println("Error reporting quote:", err)
+		return ErrReportingQuote
+	}
+
+	if s.mod.HideAfterReports > 0 && open >= s.mod.HideAfterReports {
+		// The report is stored either way, so a failure here is only logged.
+		if err := s.repo.HideQuote(ctx, quoteId, now); err != nil {
+			log.Println("Error hiding reported quote:", err)
+		} else {
+			log.Printf("Quote %s hidden after %d reports", quoteId, open)
+		}
 	}
-	return unapprovedQuotes, nil
+	return nil
+}
+
+// GetReportSummaries returns the quotes with open reports, most reported
+// first, for triage.
+func (s *QuoteServiceImpl) GetReportSummaries(ctx context.Context, a actor.Actor, limit int) ([]*ReportSummary, error) {
+	if !policy.Can(a, policy.ReportManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, ErrNotAuthorized
+	}
+	summaries, err := s.repo.GetReportSummaries(ctx, limit)
+	if err != nil {
+		log.Println("Error getting reports:", err)
+		return nil, ErrGettingReports
+	}
+	return summaries, nil
+}
+
+// GetReports returns every report ever made on a quote, newest first.
+func (s *QuoteServiceImpl) GetReports(ctx context.Context, a actor.Actor, quoteId string) ([]*Report, error) {
+	if !policy.Can(a, policy.ReportManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, ErrNotAuthorized
+	}
+	reports, err := s.repo.GetReports(ctx, quoteId)
+	if err != nil {
+		log.Println("Error getting reports:", err)
+		return nil, ErrGettingReports
+	}
+	return reports, nil
+}
+
+// ResolveReports closes the open reports on a quote. Dismissing them puts a
+// hidden quote back online; upholding them rejects the quote, taking it down
+// if it is still published. reason defaults to a generic rejection reason.
+func (s *QuoteServiceImpl) ResolveReports(ctx context.Context, a actor.Actor, quoteId, action, reason string) error {
+	if a.UID == "" || quoteId == "" || (action != ResolveDismiss && action != ResolveUphold) {
+		log.Println("Error: Invalid request body")
+		return ErrInvalidRequestBody
+	}
+	if !policy.Can(a, policy.ReportManage, policy.Any) || !policy.Can(a, policy.QuoteApprove, policy.Any) {
+		log.Println("Error: Not authorized")
+		return ErrNotAuthorized
+	}
+
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return ErrQuoteNotFound
+		}
+		log.Println("Error resolving reports:", err)
+		return ErrResolvingReports
+	}
+	reports, err := s.repo.GetReports(ctx, quoteId)
+	if err != nil {
+		log.Println("Error resolving reports:", err)
+		return ErrResolvingReports
+	}
+	if !slices.ContainsFunc(reports, func(r *Report) bool { return r.Status == ReportOpen }) {
+		return ErrNoOpenReports
+	}
+
+	hidden := quoteGotten.HiddenAt != nil && quoteGotten.Status == StatusPending
+	switch {
+	case action == ResolveDismiss && hidden:
+		err = s.review(ctx, a, quoteId, StatusApproved, "")
+	case action == ResolveUphold && (hidden || quoteGotten.Status == StatusApproved):
+		if !hidden {
+			if err = s.repo.HideQuote(ctx, quoteId, time.Now()); err != nil {
+				break
+			}
+		}
+		if reason == "" {
+			reason = upheldReason
+		}
+		err = s.review(ctx, a, quoteId, StatusRejected, reason)
+	default:
+		// The quote is not published or hidden any more, so only the reports
+		// need closing.
+		outcome := ReportDismissed
+		if action == ResolveUphold {
+			outcome = ReportUpheld
+		}
+		_, err = s.repo.ResolveReports(ctx, quoteId, outcome, a.UID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
+		log.Println("Error resolving reports:", err)
+		return ErrResolvingReports
+	}
+	return nil
+}
+
+// LikeQuote adds a's like to a published quote and returns its like count.
+// Liking a quote again changes nothing.
+func (s *QuoteServiceImpl) LikeQuote(ctx context.Context, a actor.Actor, quoteId string) (int, error) {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return 0, ErrInvalidRequestBody
+	}
+	if !policy.Can(a, policy.QuoteLike, policy.Any) {
+		log.Println("Error: Not authorized")
+		return 0, ErrNotAuthorized
+	}
+	quoteGotten, err := s.repo.GetQuoteById(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return 0, ErrQuoteNotFound
+		}
+		log.Println("Error liking quote:", err)
+		return 0, ErrLikingQuote
+	}
+	if quoteGotten.Status != StatusApproved {
+		log.Printf("Error: quote %s is %s and cannot be liked", quoteId, quoteGotten.Status)
+		return 0, ErrCannotLike
+	}
+	return s.setLike(ctx, a, quoteId, true)
+}
+
+// UnlikeQuote removes a's like from a quote, whatever its status, and returns
+// its like count. Unliking a quote that was not liked changes nothing.
+func (s *QuoteServiceImpl) UnlikeQuote(ctx context.Context, a actor.Actor, quoteId string) (int, error) {
+	if a.UID == "" || quoteId == "" {
+		log.Println("Error: Invalid request body")
+		return 0, ErrInvalidRequestBody
+	}
+	if !policy.Can(a, policy.QuoteLike, policy.Any) {
+		log.Println("Error: Not authorized")
+		return 0, ErrNotAuthorized
+	}
+	return s.setLike(ctx, a, quoteId, false)
+}
+
+func (s *QuoteServiceImpl) setLike(ctx context.Context, a actor.Actor, quoteId string, liked bool) (int, error) {
+	count, err := s.repo.SetLike(ctx, quoteId, a.UID, liked)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			log.Println("Error: Quote not found")
+			return 0, ErrQuoteNotFound
+		}
+		log.Println("Error updating like:", err)
+		return 0, ErrLikingQuote
+	}
+	return count, nil
+}
+
+// GetLikedQuotes returns one page of the published quotes a likes.
+func (s *QuoteServiceImpl) GetLikedQuotes(ctx context.Context, a actor.Actor, p page.Params) ([]*Quote, string, error) {
+	if a.UID == "" {
+		log.Println("Error: Invalid request body")
+		return nil, "", ErrInvalidRequestBody
+	}
+	quotes, err := s.repo.ListQuotes(ctx, QuoteFilter{LikedBy: a.UID, Status: StatusApproved}, p)
+	if err != nil {
+		if errors.Is(err, ErrQuoteNotFound) {
+			return []*Quote{}, "", nil
+		}
+		log.Println("Error getting liked quotes:", err)
+		return nil, "", ErrGettingQuote
+	}
+	hideReview(quotes)
+
+	quotes, next := page.Trim(quotes, p, quoteCursor)
+	return quotes, next, nil
 }
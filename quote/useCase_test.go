@@ -0,0 +1,1022 @@
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/automod"
+	"github.com/cprime50/fire-go/db"
+	"github.com/cprime50/fire-go/page"
+	"github.com/cprime50/fire-go/policy"
+)
+
+var (
+	testDb    *sql.DB
+	firstPage = page.Params{Limit: page.DefaultLimit, Sort: page.Newest}
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	testDb, err = db.ConnectTest()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = db.Migrate(testDb); err != nil {
+		log.Fatal(err)
+	}
+	defer testDb.Close()
+
+	os.Exit(m.Run())
+}
+
+func clearQuotes(t *testing.T) {
+	t.Helper()
+	if _, err := testDb.Exec("DELETE FROM quotes; DELETE FROM quote_minhash_bands; DELETE FROM quote_reports; DELETE FROM quote_likes; DELETE FROM quote_tags; DELETE FROM tags; DELETE FROM sources; DELETE FROM authors"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testActor(uid, role string) actor.Actor {
+	return actor.Actor{UID: uid, Roles: []string{role}, Permissions: policy.Default().PermissionsFor(role)}
+}
+
+// createTestQuote creates a quote through s and returns it as stored.
+func createTestQuote(t *testing.T, s *QuoteServiceImpl, a actor.Actor, text string, draft bool) *Quote {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.CreateQuote(ctx, a, text, nil, nil, draft); err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	quotes, err := s.repo.ListQuotes(ctx, QuoteFilter{UserId: a.UID}, firstPage)
+	if err != nil {
+		t.Fatalf("GetQuotesByUserId error: %v", err)
+	}
+	for _, q := range quotes {
+		if q.Quote == text {
+			return q
+		}
+	}
+	t.Fatalf("quote %q not stored", text)
+	return nil
+}
+
+func TestStatusCanTransition(t *testing.T) {
+	testCases := []struct {
+		from, to Status
+		want     bool
+	}{
+		{StatusDraft, StatusPending, true},
+		{StatusDraft, StatusApproved, false},
+		{StatusPending, StatusApproved, true},
+		{StatusPending, StatusRejected, true},
+		{StatusApproved, StatusRejected, false},
+		{StatusApproved, StatusPending, false},
+		{StatusRejected, StatusApproved, false},
+		{StatusRejected, StatusPending, true},
+		{StatusWithdrawn, StatusApproved, false},
+		{StatusWithdrawn, StatusPending, true},
+	}
+	for _, tc := range testCases {
+		if got := tc.from.CanTransition(tc.to); got != tc.want {
+			t.Errorf("%s -> %s: expected %v, got %v", tc.from, tc.to, tc.want, got)
+		}
+	}
+}
+
+func TestQuoteServiceModeration(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+
+	q := createTestQuote(t, s, author, "Simplicity is prerequisite for reliability.", false)
+	if q.Status != StatusPending {
+		t.Fatalf("CreateQuote error: expected pending, got %s", q.Status)
+	}
+
+	// Test case 1: the author cannot reject
+	if err := s.RejectQuote(ctx, author, q.Id, "nope"); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("RejectQuote error: expected ErrNotAuthorized, got %v", err)
+	}
+
+	// Test case 2: a reason is required
+	if err := s.RejectQuote(ctx, moderator, q.Id, ""); !errors.Is(err, ErrInvalidRequestBody) {
+		t.Errorf("RejectQuote error: expected ErrInvalidRequestBody, got %v", err)
+	}
+
+	// Test case 3: a moderator rejects and the author sees why
+	if err := s.RejectQuote(ctx, moderator, q.Id, "Needs attribution"); err != nil {
+		t.Fatalf("RejectQuote error: %v", err)
+	}
+	quotes, _, err := s.GetQuotesByUserId(ctx, author, author.UID, QuoteFilter{}, firstPage)
+	if err != nil {
+		t.Fatalf("GetQuotesByUserId error: %v", err)
+	}
+	if quotes[0].Status != StatusRejected || quotes[0].RejectionReason != "Needs attribution" || quotes[0].ReviewedBy != "mod1" || quotes[0].ReviewedAt == nil {
+		t.Errorf("GetQuotesByUserId error: unexpected quote %+v", quotes[0])
+	}
+
+	// Test case 4: a rejected quote cannot be approved directly
+	if err := s.ApproveQuote(ctx, moderator, q.Id); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ApproveQuote error: expected ErrInvalidTransition, got %v", err)
+	}
+
+	// Test case 5: editing sends it back to the queue without the old review, and then it can be approved
+	if err := s.UpdateQuote(ctx, author, q.Id, "Simplicity is prerequisite for reliability. - Dijkstra", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	quotes, _, err = s.GetQuotesByUserId(ctx, author, author.UID, QuoteFilter{}, firstPage)
+	if err != nil {
+		t.Fatalf("GetQuotesByUserId error: %v", err)
+	}
+	if quotes[0].Status != StatusPending || quotes[0].RejectionReason != "" || quotes[0].ReviewedBy != "" || quotes[0].ReviewedAt != nil {
+		t.Errorf("UpdateQuote error: expected a pending quote without the rejection, got %+v", quotes[0])
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+
+	// Test case 6: other users see the approved quote without review details
+	quotes, _, err = s.GetQuotesByUserId(ctx, testActor("reader1", policy.RoleUser), author.UID, QuoteFilter{}, firstPage)
+	if err != nil {
+		t.Fatalf("GetQuotesByUserId error: %v", err)
+	}
+	if !quotes[0].Approved || quotes[0].ReviewedBy != "" || quotes[0].RejectionReason != "" {
+		t.Errorf("GetQuotesByUserId error: unexpected public quote %+v", quotes[0])
+	}
+
+	// Test case 7: an edit based on a status read before the approval does not overwrite the approved text
+	repo := NewQuoteRepository(testDb)
+	err = repo.UpdateQuote(ctx, &Quote{Id: q.Id, Quote: "Simplicity is overrated", Status: StatusPending}, StatusPending, author.UID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("UpdateQuote error: expected ErrInvalidTransition, got %v", err)
+	}
+	approved, err := repo.GetQuoteById(ctx, q.Id)
+	if err != nil {
+		t.Fatalf("GetQuoteById error: %v", err)
+	}
+	if approved.Status != StatusApproved || approved.Quote != "Simplicity is prerequisite for reliability. - Dijkstra" {
+		t.Errorf("UpdateQuote error: expected the approved quote untouched, got %+v", approved)
+	}
+}
+
+func TestQuoteServiceDraftAndWithdraw(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+
+	q := createTestQuote(t, s, author, "Talk is cheap. Show me the code.", true)
+
+	// Test case 1: drafts are not in the moderation queue
+	if _, _, err := s.GetUnapprovedQuotes(ctx, moderator, QuoteFilter{}, firstPage); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("GetUnapprovedQuotes error: expected ErrQuoteNotFound, got %v", err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ApproveQuote error: expected ErrInvalidTransition, got %v", err)
+	}
+
+	// Test case 2: only the author can submit it
+	if err := s.SubmitQuote(ctx, testActor("other1", policy.RoleUser), q.Id); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("SubmitQuote error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.SubmitQuote(ctx, author, q.Id); err != nil {
+		t.Fatalf("SubmitQuote error: %v", err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+
+	// Test case 3: submitting an approved quote again leaves it published
+	if err := s.SubmitQuote(ctx, author, q.Id); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("SubmitQuote error: expected ErrInvalidTransition, got %v", err)
+	}
+	if approved, err := s.repo.GetQuoteById(ctx, q.Id); err != nil || approved.Status != StatusApproved {
+		t.Errorf("SubmitQuote error: expected the quote to stay approved, got %+v, %v", approved, err)
+	}
+
+	// Test case 4: a withdrawn quote leaves public listings
+	if err := s.WithdrawQuote(ctx, author, q.Id); err != nil {
+		t.Fatalf("WithdrawQuote error: %v", err)
+	}
+	if _, _, err := s.GetQuotes(ctx, testActor("reader1", policy.RoleUser), QuoteFilter{}, firstPage); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("GetQuotes error: expected ErrQuoteNotFound, got %v", err)
+	}
+	if err := s.WithdrawQuote(ctx, author, q.Id); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("WithdrawQuote error: expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestQuoteServiceRevisions(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	admin := testActor("admin1", policy.RoleAdmin)
+
+	q := createTestQuote(t, s, author, "Make it work, make it right", false)
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	if err := s.UpdateQuote(ctx, author, q.Id, "Make it work, make it fast", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	if err := s.RejectQuote(ctx, moderator, q.Id, "Misquoted"); err != nil {
+		t.Fatalf("RejectQuote error: %v", err)
+	}
+
+	// Test case 1: every edit is kept with its outcome
+	revisions, err := s.GetRevisions(ctx, author, q.Id)
+	if err != nil {
+		t.Fatalf("GetRevisions error: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Status != StatusApproved || revisions[1].Status != StatusRejected || revisions[1].RejectionReason != "Misquoted" {
+		t.Fatalf("GetRevisions error: unexpected revisions %+v %+v", revisions[0], revisions[len(revisions)-1])
+	}
+
+	// Test case 2: other users cannot read the history
+	if _, err := s.GetRevisions(ctx, testActor("reader1", policy.RoleUser), q.Id); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("GetRevisions error: expected ErrNotAuthorized, got %v", err)
+	}
+
+	// Test case 3: diff between the two revisions
+	diff, err := s.DiffRevisions(ctx, moderator, q.Id, 1, 2)
+	if err != nil {
+		t.Fatalf("DiffRevisions error: %v", err)
+	}
+	want := []DiffChunk{{DiffEqual, "Make it work, make it"}, {DiffDelete, "right"}, {DiffInsert, "fast"}}
+	if len(diff.Chunks) != len(want) || diff.Chunks[1] != want[1] || diff.Chunks[2] != want[2] {
+		t.Errorf("DiffRevisions error: unexpected chunks %v", diff.Chunks)
+	}
+
+	// Test case 4: only approved revisions can be restored, and only by admins
+	if err := s.RollbackQuote(ctx, admin, q.Id, 2); !errors.Is(err, ErrRevisionNotApproved) {
+		t.Errorf("RollbackQuote error: expected ErrRevisionNotApproved, got %v", err)
+	}
+	if err := s.RollbackQuote(ctx, moderator, q.Id, 1); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("RollbackQuote error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.RollbackQuote(ctx, admin, q.Id, 1); err != nil {
+		t.Fatalf("RollbackQuote error: %v", err)
+	}
+	restored, err := s.repo.GetQuoteById(ctx, q.Id)
+	if err != nil {
+		t.Fatalf("GetQuoteById error: %v", err)
+	}
+	if restored.Quote != "Make it work, make it right" || restored.Status != StatusApproved {
+		t.Errorf("RollbackQuote error: unexpected quote %+v", restored)
+	}
+	revisions, _ = s.GetRevisions(ctx, author, q.Id)
+	if len(revisions) != 3 || revisions[2].EditedBy != "admin1" {
+		t.Errorf("RollbackQuote error: expected a third revision by the admin, got %d", len(revisions))
+	}
+
+	// Test case 5: deleting the quote deletes its history
+	if err := s.DeleteQuote(ctx, author, q.Id); err != nil {
+		t.Fatalf("DeleteQuote error: %v", err)
+	}
+	if _, err := s.repo.GetRevisions(ctx, q.Id); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("DeleteQuote error: expected revisions gone, got %v", err)
+	}
+}
+
+func TestQuoteServiceEditApprovedQuote(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	reader := testActor("reader1", policy.RoleUser)
+
+	q := createTestQuote(t, s, author, "Premature optimization is the root of all evil", false)
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	if err := s.UpdateQuote(ctx, author, q.Id, "Premature optimisation is the root of all evil", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	if err := s.UpdateQuote(ctx, author, q.Id, "Premature optimization is the root of all evil. - Knuth", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+
+	// Test case 1: readers keep seeing the approved text
+	quotes, _, err := s.GetQuotes(ctx, reader, QuoteFilter{}, firstPage)
+	if err != nil {
+		t.Fatalf("GetQuotes error: %v", err)
+	}
+	if quotes[0].Quote != q.Quote || quotes[0].PendingEdit != nil {
+		t.Errorf("GetQuotes error: unexpected quote %+v", quotes[0])
+	}
+
+	// Test case 2: the latest edit is in the queue and the first one was superseded
+	queue, _, err := s.GetUnapprovedQuotes(ctx, moderator, QuoteFilter{}, firstPage)
+	if err != nil {
+		t.Fatalf("GetUnapprovedQuotes error: %v", err)
+	}
+	if len(queue) != 1 || queue[0].PendingEdit == nil || queue[0].PendingEdit.Revision != 3 {
+		t.Fatalf("GetUnapprovedQuotes error: unexpected queue %+v", queue)
+	}
+	rev, _ := s.repo.GetRevision(ctx, q.Id, 2)
+	if rev.Status != StatusSuperseded {
+		t.Errorf("UpdateQuote error: expected revision 2 superseded, got %s", rev.Status)
+	}
+
+	// Test case 3: rejecting the edit keeps the approved text
+	if err := s.RejectQuote(ctx, moderator, q.Id, "Keep the original"); err != nil {
+		t.Fatalf("RejectQuote error: %v", err)
+	}
+	current, _ := s.repo.GetQuoteById(ctx, q.Id)
+	if current.Status != StatusApproved || current.Quote != q.Quote {
+		t.Errorf("RejectQuote error: unexpected quote %+v", current)
+	}
+	if _, _, err := s.GetUnapprovedQuotes(ctx, moderator, QuoteFilter{}, firstPage); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("GetUnapprovedQuotes error: expected an empty queue, got %v", err)
+	}
+
+	// Test case 4: approving an edit makes it live
+	if err := s.UpdateQuote(ctx, author, q.Id, "Premature optimization is the root of all evil. - Knuth", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	own, _, _ := s.GetQuotesByUserId(ctx, author, author.UID, QuoteFilter{}, firstPage)
+	if own[0].PendingEdit == nil {
+		t.Error("GetQuotesByUserId error: expected the author to see the pending edit")
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	quotes, _, _ = s.GetQuotes(ctx, reader, QuoteFilter{}, firstPage)
+	if quotes[0].Quote != "Premature optimization is the root of all evil. - Knuth" {
+		t.Errorf("ApproveQuote error: unexpected quote %+v", quotes[0])
+	}
+
+	// Test case 5: with nothing pending, an approved quote cannot be approved again
+	if err := s.ApproveQuote(ctx, moderator, q.Id); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ApproveQuote error: expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestQuoteServicePagination(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	admin := testActor("admin1", policy.RoleAdmin)
+
+	var ids []string
+	for _, text := range []string{"one", "two", "three", "four", "five"} {
+		q := createTestQuote(t, s, author, text, false)
+		ids = append(ids, q.Id)
+	}
+	for _, id := range ids[:4] {
+		if err := s.ApproveQuote(ctx, moderator, id); err != nil {
+			t.Fatalf("ApproveQuote error: %v", err)
+		}
+	}
+
+	// Test case 1: walking the pages returns every approved quote once, newest first
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		p, err := page.Parse("3", cursor, "")
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		quotes, next, err := s.GetQuotes(ctx, author, QuoteFilter{}, p)
+		if err != nil {
+			t.Fatalf("GetQuotes error: %v", err)
+		}
+		for _, q := range quotes {
+			got = append(got, q.Quote)
+		}
+		if next == "" {
+			break
+		}
+		if pages > 2 {
+			t.Fatal("GetQuotes error: pagination does not end")
+		}
+		cursor = next
+	}
+	if want := []string{"four", "three", "two", "one"}; !slices.Equal(got, want) {
+		t.Errorf("GetQuotes error: expected %v, got %v", want, got)
+	}
+
+	// Test case 2: oldest first
+	p, _ := page.Parse("2", "", page.Oldest)
+	quotes, next, err := s.GetQuotes(ctx, author, QuoteFilter{}, p)
+	if err != nil || len(quotes) != 2 || quotes[0].Quote != "one" || next == "" {
+		t.Errorf("GetQuotes error: unexpected first page %v, %q, %v", quotes, next, err)
+	}
+
+	// Test case 3: filtering by status needs permission to read everything
+	if _, _, err := s.GetQuotes(ctx, author, QuoteFilter{Status: StatusPending}, firstPage); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("GetQuotes error: expected ErrNotAuthorized, got %v", err)
+	}
+	quotes, _, err = s.GetQuotes(ctx, admin, QuoteFilter{Status: StatusPending}, firstPage)
+	if err != nil || len(quotes) != 1 || quotes[0].Quote != "five" {
+		t.Errorf("GetQuotes error: unexpected pending quotes %v, %v", quotes, err)
+	}
+
+	// Test case 4: date range
+	if _, _, err := s.GetQuotes(ctx, admin, QuoteFilter{To: time.Now().Add(-time.Hour)}, firstPage); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("GetQuotes error: expected ErrQuoteNotFound, got %v", err)
+	}
+	quotes, _, err = s.GetQuotes(ctx, admin, QuoteFilter{From: time.Now().Add(-time.Hour)}, firstPage)
+	if err != nil || len(quotes) != 5 {
+		t.Errorf("GetQuotes error: expected 5 quotes, got %d, %v", len(quotes), err)
+	}
+}
+
+func TestQuoteServiceSearch(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	reader := testActor("reader1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	admin := testActor("admin1", policy.RoleAdmin)
+
+	simple := createTestQuote(t, s, author, "Simple is better than complex, and simple things stay simple", false)
+	flat := createTestQuote(t, s, author, "Flat is better than nested", false)
+	hidden := createTestQuote(t, s, author, "Simplicity is hidden until approved", false)
+	for _, q := range []*Quote{simple, flat} {
+		if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+			t.Fatalf("ApproveQuote error: %v", err)
+		}
+	}
+
+	// Test case 1: prefix matching, with the best match first and highlighted
+	results, err := s.SearchQuotes(ctx, reader, "simpl", 10)
+	if err != nil {
+		t.Fatalf("SearchQuotes error: %v", err)
+	}
+	if len(results) != 1 || results[0].Id != simple.Id {
+		t.Fatalf("SearchQuotes error: expected only the approved match, got %d results", len(results))
+	}
+	if results[0].Snippet != "<mark>Simple</mark> is better than complex, and <mark>simple</mark> things stay <mark>simple</mark>" {
+		t.Errorf("SearchQuotes error: unexpected snippet %q", results[0].Snippet)
+	}
+
+	// Test case 2: authors and admins also find unapproved quotes
+	for _, a := range []actor.Actor{author, admin} {
+		results, err = s.SearchQuotes(ctx, a, "simpl", 10)
+		if err != nil || len(results) != 2 {
+			t.Fatalf("SearchQuotes error: expected 2 results for %s, got %d, %v", a.UID, len(results), err)
+		}
+		if results[0].Id != simple.Id || results[0].Rank >= results[1].Rank {
+			t.Errorf("SearchQuotes error: expected the repeated match to rank first, got %+v", results)
+		}
+	}
+
+	// Test case 3: every word must match
+	results, err = s.SearchQuotes(ctx, reader, "better nested", 10)
+	if err != nil || len(results) != 1 || results[0].Id != flat.Id {
+		t.Errorf("SearchQuotes error: unexpected results %v, %v", results, err)
+	}
+
+	// Test case 4: the index follows edits and deletes
+	if err := s.UpdateQuote(ctx, author, hidden.Id, "Readability counts", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	if _, err := s.SearchQuotes(ctx, author, "hidden", 10); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("SearchQuotes error: expected the old text to be gone, got %v", err)
+	}
+	if err := s.DeleteQuote(ctx, author, flat.Id); err != nil {
+		t.Fatalf("DeleteQuote error: %v", err)
+	}
+	if _, err := s.SearchQuotes(ctx, admin, "nested", 10); !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("SearchQuotes error: expected the deleted quote to be gone, got %v", err)
+	}
+
+	// Test case 5: nothing to search for
+	if _, err := s.SearchQuotes(ctx, reader, " * ", 10); !errors.Is(err, ErrInvalidRequestBody) {
+		t.Errorf("SearchQuotes error: expected ErrInvalidRequestBody, got %v", err)
+	}
+}
+
+func TestQuoteServiceTags(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	admin := testActor("admin1", policy.RoleAdmin)
+
+	// Test case 1: tags are normalized and only counted once approved
+	if err := s.CreateQuote(ctx, author, "Less is more", []string{"Design", " design ", "Minimalism!"}, nil, false); err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	quotes, err := s.repo.ListQuotes(ctx, QuoteFilter{UserId: author.UID}, firstPage)
+	if err != nil || len(quotes) != 1 {
+		t.Fatalf("ListQuotes error: expected 1 quote, got %v, %v", quotes, err)
+	}
+	q := quotes[0]
+	if !slices.Equal(q.Tags, []string{"design", "minimalism"}) {
+		t.Errorf("CreateQuote error: unexpected tags %v", q.Tags)
+	}
+	if counts, err := s.GetTagCounts(ctx, author, 10); err != nil || len(counts) != 0 {
+		t.Errorf("GetTagCounts error: expected no approved tags, got %v, %v", counts, err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	other := createTestQuote(t, s, author, "Form follows function", false)
+	if err := s.UpdateQuote(ctx, author, other.Id, "Form follows function", []string{"design"}, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, other.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	counts, err := s.GetTagCounts(ctx, author, 10)
+	if err != nil || len(counts) != 2 || *counts[0] != (TagCount{Slug: "design", Count: 2}) {
+		t.Errorf("GetTagCounts error: unexpected counts %v, %v", counts, err)
+	}
+
+	// Test case 2: invalid tags are rejected
+	if err := s.CreateQuote(ctx, author, "Nope", []string{"!!"}, nil, false); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("CreateQuote error: expected ErrInvalidTag, got %v", err)
+	}
+	if err := s.CreateQuote(ctx, author, "Nope", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}, nil, false); !errors.Is(err, ErrTooManyTags) {
+		t.Errorf("CreateQuote error: expected ErrTooManyTags, got %v", err)
+	}
+
+	// Test case 3: tag changes on an approved quote wait for review
+	if err := s.UpdateQuote(ctx, author, q.Id, "Less is more", []string{"design", "architecture"}, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	quotes, _, err = s.GetQuotes(ctx, author, QuoteFilter{Tag: "architecture"}, firstPage)
+	if !errors.Is(err, ErrQuoteNotFound) {
+		t.Errorf("GetQuotes error: expected the proposed tag to stay hidden, got %v, %v", quotes, err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	quotes, _, err = s.GetQuotes(ctx, author, QuoteFilter{Tag: "architecture"}, firstPage)
+	if err != nil || len(quotes) != 1 || !slices.Equal(quotes[0].Tags, []string{"architecture", "design"}) {
+		t.Errorf("GetQuotes error: unexpected quotes %v, %v", quotes, err)
+	}
+
+	// Test case 4: only admins rename and merge tags
+	if err := s.RenameTag(ctx, moderator, "design", "craft"); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("RenameTag error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.RenameTag(ctx, admin, "design", "architecture"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("RenameTag error: expected ErrTagExists, got %v", err)
+	}
+	if err := s.RenameTag(ctx, admin, "nope", "craft"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("RenameTag error: expected ErrTagNotFound, got %v", err)
+	}
+	if err := s.RenameTag(ctx, admin, "design", "Craft"); err != nil {
+		t.Fatalf("RenameTag error: %v", err)
+	}
+	if err := s.MergeTags(ctx, admin, "architecture", "craft"); err != nil {
+		t.Fatalf("MergeTags error: %v", err)
+	}
+	counts, err = s.GetTagCounts(ctx, author, 10)
+	if err != nil || len(counts) != 1 || *counts[0] != (TagCount{Slug: "craft", Count: 2}) {
+		t.Errorf("GetTagCounts error: unexpected counts %v, %v", counts, err)
+	}
+	revisions, err := s.GetRevisions(ctx, author, q.Id)
+	if err != nil {
+		t.Fatalf("GetRevisions error: %v", err)
+	}
+	if tags := revisions[len(revisions)-1].Tags; !slices.Equal(tags, []string{"craft"}) {
+		t.Errorf("MergeTags error: expected revisions to be retagged, got %v", tags)
+	}
+}
+
+func TestQuoteServiceAuthors(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	admin := testActor("admin1", policy.RoleAdmin)
+	year := func(y int) *int { return &y }
+
+	// Test case 1: authors and sources are created once and matched by name
+	twain := &AttributionRequest{Author: "Mark  Twain", Born: year(1835), Source: "Following the Equator", Year: year(1897), Page: "12"}
+	if err := s.CreateQuote(ctx, author, "Truth is stranger than fiction", nil, twain, false); err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	again := &AttributionRequest{Author: "mark twain.", Died: year(1910), Source: "following the equator"}
+	if err := s.CreateQuote(ctx, author, "Man is the only animal that blushes", nil, again, false); err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	quotes, err := s.repo.ListQuotes(ctx, QuoteFilter{UserId: author.UID}, firstPage)
+	if err != nil || len(quotes) != 2 {
+		t.Fatalf("ListQuotes error: expected 2 quotes, got %v, %v", quotes, err)
+	}
+	truth, blush := quotes[1], quotes[0]
+	if truth.Author == nil || truth.AuthorId != blush.AuthorId || truth.SourceId != blush.SourceId {
+		t.Fatalf("CreateQuote error: expected one author and source, got %+v and %+v", truth.Attribution, blush.Attribution)
+	}
+	if truth.Author.Name != "Mark Twain" || *truth.Author.Born != 1835 || *truth.Author.Died != 1910 {
+		t.Errorf("CreateQuote error: unexpected author %+v", truth.Author)
+	}
+	if truth.Source.Title != "Following the Equator" || *truth.Source.Year != 1897 || truth.Page != "12" || blush.Page != "" {
+		t.Errorf("CreateQuote error: unexpected source %+v, page %q", truth.Source, truth.Page)
+	}
+
+	// Test case 2: invalid attributions are rejected
+	for _, at := range []*AttributionRequest{
+		{Source: "No author"},
+		{Author: "...", Source: "Nothing"},
+		{Author: "Time traveller", Born: year(2100)},
+		{Author: "Backwards", Born: year(1900), Died: year(1800)},
+	} {
+		if err := s.CreateQuote(ctx, author, "Nope", nil, at, false); !errors.Is(err, ErrInvalidAttribution) {
+			t.Errorf("CreateQuote error: expected ErrInvalidAttribution for %+v, got %v", at, err)
+		}
+	}
+
+	// Test case 3: a duplicate author merges into the original, sources too
+	short := &AttributionRequest{Author: "M. Twain", Source: "Following the Equator!"}
+	if err := s.UpdateQuote(ctx, author, blush.Id, blush.Quote, nil, short); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	for _, q := range []*Quote{truth, blush} {
+		if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+			t.Fatalf("ApproveQuote error: %v", err)
+		}
+	}
+	blush, _ = s.repo.GetQuoteById(ctx, blush.Id)
+	if blush.AuthorId == truth.AuthorId || blush.Author.Name != "M. Twain" {
+		t.Fatalf("UpdateQuote error: expected a new author, got %+v", blush.Author)
+	}
+	if err := s.MergeAuthors(ctx, moderator, blush.AuthorId, truth.AuthorId); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("MergeAuthors error: expected ErrNotAuthorized, got %v", err)
+	}
+	if err := s.MergeAuthors(ctx, admin, "nope", truth.AuthorId); !errors.Is(err, ErrAuthorNotFound) {
+		t.Errorf("MergeAuthors error: expected ErrAuthorNotFound, got %v", err)
+	}
+	if err := s.MergeAuthors(ctx, admin, blush.AuthorId, truth.AuthorId); err != nil {
+		t.Fatalf("MergeAuthors error: %v", err)
+	}
+	if _, _, err := s.GetAuthor(ctx, author, blush.AuthorId); !errors.Is(err, ErrAuthorNotFound) {
+		t.Errorf("GetAuthor error: expected the merged author to be gone, got %v", err)
+	}
+	got, sources, err := s.GetAuthor(ctx, author, truth.AuthorId)
+	if err != nil || got.Name != "Mark Twain" || len(sources) != 1 {
+		t.Errorf("GetAuthor error: unexpected author %+v with sources %v, %v", got, sources, err)
+	}
+
+	// Test case 4: browsing an author's quotes
+	quotes, _, err = s.GetQuotes(ctx, author, QuoteFilter{AuthorId: truth.AuthorId}, firstPage)
+	if err != nil || len(quotes) != 2 || quotes[0].SourceId != truth.SourceId {
+		t.Errorf("GetQuotes error: expected both quotes from one source, got %v, %v", quotes, err)
+	}
+	authors, next, err := s.GetAuthors(ctx, author, firstPage)
+	if err != nil || len(authors) != 1 || next != "" {
+		t.Errorf("GetAuthors error: expected 1 author, got %v, %q, %v", authors, next, err)
+	}
+}
+
+func TestQuoteServiceDuplicates(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	other := testActor("author2", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+
+	original := createTestQuote(t, s, author, "The only way to do great work is to love what you do.", false)
+
+	// Test case 1: exact duplicates are refused, naming the existing quote
+	err := s.CreateQuote(ctx, other, "the only way to do GREAT work, is to love what you do!", nil, nil, false)
+	var duplicate *DuplicateError
+	if !errors.As(err, &duplicate) || !errors.Is(err, ErrDuplicateQuote) {
+		t.Fatalf("CreateQuote error: expected a DuplicateError, got %v", err)
+	}
+	if duplicate.QuoteId != original.Id || duplicate.Similarity != 1 {
+		t.Errorf("CreateQuote error: unexpected duplicate %+v", duplicate.Duplicate)
+	}
+
+	// Test case 2: near duplicates are queued with a hint for moderators
+	near := createTestQuote(t, s, other, "The only way to do good work is to love what you do.", false)
+	unrelated := createTestQuote(t, s, other, "Simplicity is prerequisite for reliability.", false)
+	quotes, _, err := s.GetUnapprovedQuotes(ctx, moderator, QuoteFilter{}, firstPage)
+	if err != nil {
+		t.Fatalf("GetUnapprovedQuotes error: %v", err)
+	}
+	hints := map[string]*Duplicate{}
+	for _, q := range quotes {
+		hints[q.Id] = q.PossibleDuplicateOf
+	}
+	if hint := hints[near.Id]; hint == nil || hint.QuoteId != original.Id || hint.Similarity >= DuplicateThreshold {
+		t.Errorf("GetUnapprovedQuotes error: unexpected hint %+v for the near duplicate", hint)
+	}
+	if hint := hints[unrelated.Id]; hint != nil {
+		t.Errorf("GetUnapprovedQuotes error: unexpected hint %+v for an unrelated quote", hint)
+	}
+
+	// Test case 3: withdrawn quotes no longer count
+	if err := s.WithdrawQuote(ctx, author, original.Id); err != nil {
+		t.Fatalf("WithdrawQuote error: %v", err)
+	}
+	if err := s.CreateQuote(ctx, other, original.Quote, nil, nil, false); err != nil {
+		t.Errorf("CreateQuote error: %v", err)
+	}
+
+	// Test case 4: quotes saved before fingerprinting are backfilled
+	_, err = testDb.Exec("INSERT INTO quotes (id, user_id, quote, status, created_at) VALUES ('old', 'author1', 'Readability counts.', 'approved', $1)", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.repo.(*SQLiteQuoteRepository).BackfillFingerprints(ctx); err != nil || n != 1 {
+		t.Fatalf("BackfillFingerprints error: expected 1 quote, got %d, %v", n, err)
+	}
+	if err := s.CreateQuote(ctx, other, "readability COUNTS", nil, nil, false); !errors.Is(err, ErrDuplicateQuote) {
+		t.Errorf("CreateQuote error: expected ErrDuplicateQuote, got %v", err)
+	}
+}
+
+func TestQuoteServiceAutomod(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	mod := automod.Default()
+	mod.TrustedAfter = 1
+	learner := &recordingLearner{}
+	mod.Chain = append(mod.Chain, learner)
+	s := NewQuoteService(NewQuoteRepository(testDb), mod)
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+
+	// Test case 1: rejected submissions are refused with the reasons
+	err := s.CreateQuote(ctx, author, "no", nil, nil, false)
+	var rejected *AutoRejectedError
+	if !errors.As(err, &rejected) || !errors.Is(err, ErrAutoRejected) || len(rejected.Reasons) != 1 {
+		t.Fatalf("CreateQuote error: expected an AutoRejectedError, got %v", err)
+	}
+
+	// Test case 2: the queue can be sorted riskiest first, page by page
+	clean := createTestQuote(t, s, author, "Well begun is half done.", false)
+	link := createTestQuote(t, s, author, "More wisdom at www.example.com", false)
+	shouting := createTestQuote(t, s, author, "CLICK HERE FOR WISDOM: www.example.com", false)
+	var order []string
+	cursor := ""
+	for {
+		p, err := page.Parse("1", cursor, SortRisk, SortRisk)
+		if err != nil {
+			t.Fatalf("page.Parse error: %v", err)
+		}
+		quotes, next, err := s.GetUnapprovedQuotes(ctx, moderator, QuoteFilter{}, p)
+		if err != nil {
+			t.Fatalf("GetUnapprovedQuotes error: %v", err)
+		}
+		for _, q := range quotes {
+			if q.Automod == nil {
+				t.Fatalf("GetUnapprovedQuotes error: no automod result on %s", q.Id)
+			}
+			order = append(order, q.Id)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if !slices.Equal(order, []string{shouting.Id, link.Id, clean.Id}) {
+		t.Errorf("GetUnapprovedQuotes error: unexpected risk order %v", order)
+	}
+
+	// Test case 3: once trusted, clean submissions skip the queue but flagged ones do not
+	if err := s.ApproveQuote(ctx, moderator, clean.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	trustedClean := createTestQuote(t, s, author, "Practice makes perfect.", false)
+	if trustedClean.Status != StatusApproved || trustedClean.ReviewedBy != automod.ReviewerId {
+		t.Errorf("CreateQuote error: expected auto-approval, got %s by %q", trustedClean.Status, trustedClean.ReviewedBy)
+	}
+	if err := s.UpdateQuote(ctx, author, trustedClean.Id, "Practice makes perfect, sooooooo practice.", nil, nil); err != nil {
+		t.Fatalf("UpdateQuote error: %v", err)
+	}
+	if edit, err := s.repo.GetPendingEdit(ctx, trustedClean.Id); err != nil || edit.Status != StatusPending {
+		t.Errorf("UpdateQuote error: expected the flagged edit to wait for review, got %v", err)
+	}
+
+	// Test case 4: moderators' decisions are learned from, automod's own are not
+	if err := s.RejectQuote(ctx, moderator, link.Id, "spam"); err != nil {
+		t.Fatalf("RejectQuote error: %v", err)
+	}
+	if !slices.Equal(learner.approved, []string{clean.Quote}) || !slices.Equal(learner.rejected, []string{link.Quote}) {
+		t.Errorf("Learn error: unexpected training, approved %q, rejected %q", learner.approved, learner.rejected)
+	}
+}
+
+// recordingLearner is an automod.Learner that remembers what it was taught.
+type recordingLearner struct {
+	approved, rejected []string
+}
+
+func (l *recordingLearner) Moderate(ctx context.Context, s automod.Submission) automod.Result {
+	return automod.Allowed
+}
+
+func (l *recordingLearner) Learn(ctx context.Context, s automod.Submission, rejected bool) error {
+	if rejected {
+		l.rejected = append(l.rejected, s.Text)
+	} else {
+		l.approved = append(l.approved, s.Text)
+	}
+	return nil
+}
+
+func TestQuoteServiceReports(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	mod := automod.Default()
+	mod.HideAfterReports = 2
+	s := NewQuoteService(NewQuoteRepository(testDb), mod)
+	author := testActor("author1", policy.RoleUser)
+	reader1 := testActor("reader1", policy.RoleUser)
+	reader2 := testActor("reader2", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	admin := testActor("admin1", policy.RoleAdmin)
+
+	q := createTestQuote(t, s, author, "Fortune favours the bold.", false)
+	if err := s.ReportQuote(ctx, reader1, q.Id, ReasonSpam, ""); !errors.Is(err, ErrCannotReport) {
+		t.Errorf("ReportQuote error: expected ErrCannotReport for a pending quote, got %v", err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+
+	// Test case 1: reports are validated and deduplicated per reader
+	if err := s.ReportQuote(ctx, reader1, q.Id, ReasonOther, " "); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("ReportQuote error: expected ErrInvalidReport, got %v", err)
+	}
+	if err := s.ReportQuote(ctx, author, q.Id, ReasonSpam, ""); !errors.Is(err, ErrCannotReport) {
+		t.Errorf("ReportQuote error: expected ErrCannotReport for one's own quote, got %v", err)
+	}
+	if err := s.ReportQuote(ctx, reader1, q.Id, ReasonMisattributed, "It was Virgil"); err != nil {
+		t.Fatalf("ReportQuote error: %v", err)
+	}
+	if err := s.ReportQuote(ctx, reader1, q.Id, ReasonSpam, ""); !errors.Is(err, ErrAlreadyReported) {
+		t.Errorf("ReportQuote error: expected ErrAlreadyReported, got %v", err)
+	}
+
+	// Test case 2: reaching the threshold hides the quote back into the queue
+	if err := s.ReportQuote(ctx, reader2, q.Id, ReasonMisattributed, ""); err != nil {
+		t.Fatalf("ReportQuote error: %v", err)
+	}
+	hidden, err := s.repo.GetQuoteById(ctx, q.Id)
+	if err != nil || hidden.Status != StatusPending || hidden.HiddenAt == nil {
+		t.Fatalf("ReportQuote error: expected the quote to be hidden, got %+v, %v", hidden, err)
+	}
+	summaries, err := s.GetReportSummaries(ctx, admin, 10)
+	if err != nil {
+		t.Fatalf("GetReportSummaries error: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].OpenReports != 2 || summaries[0].Reasons[ReasonMisattributed] != 2 {
+		t.Errorf("GetReportSummaries error: unexpected summaries %+v", summaries)
+	}
+	if _, err := s.GetReportSummaries(ctx, moderator, 10); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("GetReportSummaries error: expected ErrNotAuthorized, got %v", err)
+	}
+
+	// Test case 3: dismissing the reports publishes the quote again
+	if err := s.ResolveReports(ctx, admin, q.Id, ResolveDismiss, ""); err != nil {
+		t.Fatalf("ResolveReports error: %v", err)
+	}
+	restored, err := s.repo.GetQuoteById(ctx, q.Id)
+	if err != nil || restored.Status != StatusApproved || restored.HiddenAt != nil {
+		t.Errorf("ResolveReports error: expected the quote to be published, got %+v, %v", restored, err)
+	}
+	reports, err := s.GetReports(ctx, admin, q.Id)
+	if err != nil || len(reports) != 2 || reports[0].Status != ReportDismissed || reports[0].ResolvedBy != admin.UID {
+		t.Errorf("GetReports error: expected two dismissed reports, got %v", err)
+	}
+	if err := s.ResolveReports(ctx, admin, q.Id, ResolveDismiss, ""); !errors.Is(err, ErrNoOpenReports) {
+		t.Errorf("ResolveReports error: expected ErrNoOpenReports, got %v", err)
+	}
+
+	// Test case 4: upholding a report takes a published quote down
+	if err := s.ReportQuote(ctx, reader1, q.Id, ReasonOffensive, ""); err != nil {
+		t.Fatalf("ReportQuote error: %v", err)
+	}
+	if err := s.ResolveReports(ctx, admin, q.Id, ResolveUphold, ""); err != nil {
+		t.Fatalf("ResolveReports error: %v", err)
+	}
+	removed, err := s.repo.GetQuoteById(ctx, q.Id)
+	if err != nil || removed.Status != StatusRejected || removed.RejectionReason != upheldReason {
+		t.Errorf("ResolveReports error: expected the quote to be rejected, got %+v, %v", removed, err)
+	}
+
+	// Test case 5: rolling back a hidden quote publishes the earlier text and upholds the reports
+	other := createTestQuote(t, s, author, "Who dares wins.", false)
+	if err := s.ApproveQuote(ctx, moderator, other.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+	for _, reader := range []actor.Actor{reader1, reader2} {
+		if err := s.ReportQuote(ctx, reader, other.Id, ReasonOffensive, ""); err != nil {
+			t.Fatalf("ReportQuote error: %v", err)
+		}
+	}
+	if err := s.RollbackQuote(ctx, admin, other.Id, 1); err != nil {
+		t.Fatalf("RollbackQuote error: %v", err)
+	}
+	rolledBack, err := s.repo.GetQuoteById(ctx, other.Id)
+	if err != nil || rolledBack.Status != StatusApproved || rolledBack.HiddenAt != nil {
+		t.Errorf("RollbackQuote error: expected the quote to be published, got %+v, %v", rolledBack, err)
+	}
+	reports, err = s.GetReports(ctx, admin, other.Id)
+	if err != nil || len(reports) != 2 || reports[0].Status != ReportUpheld || reports[1].Status != ReportUpheld {
+		t.Errorf("GetReports error: expected two upheld reports, got %+v, %v", reports, err)
+	}
+}
+
+func TestQuoteServiceLikes(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	reader1 := testActor("reader1", policy.RoleUser)
+	reader2 := testActor("reader2", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+
+	q := createTestQuote(t, s, author, "Fall seven times, stand up eight.", false)
+
+	// Test case 1: only published quotes can be liked
+	if _, err := s.LikeQuote(ctx, reader1, q.Id); !errors.Is(err, ErrCannotLike) {
+		t.Errorf("LikeQuote error: expected ErrCannotLike, got %v", err)
+	}
+	if err := s.ApproveQuote(ctx, moderator, q.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+
+	// Test case 2: liking is idempotent and the count shows on listings
+	for _, a := range []actor.Actor{reader1, reader1, reader2} {
+		if _, err := s.LikeQuote(ctx, a, q.Id); err != nil {
+			t.Fatalf("LikeQuote error: %v", err)
+		}
+	}
+	quotes, _, err := s.GetQuotesByUserId(ctx, reader1, author.UID, QuoteFilter{}, firstPage)
+	if err != nil || len(quotes) != 1 || quotes[0].Likes != 2 {
+		t.Fatalf("GetQuotesByUserId error: expected 2 likes, got %v, %v", quotes, err)
+	}
+	liked, _, err := s.GetLikedQuotes(ctx, reader1, firstPage)
+	if err != nil || len(liked) != 1 || liked[0].Id != q.Id {
+		t.Errorf("GetLikedQuotes error: expected the liked quote, got %v, %v", liked, err)
+	}
+
+	// Test case 3: unliking is idempotent too
+	for i := 0; i < 2; i++ {
+		if n, err := s.UnlikeQuote(ctx, reader1, q.Id); err != nil || n != 1 {
+			t.Errorf("UnlikeQuote error: expected 1 like left, got %d, %v", n, err)
+		}
+	}
+	if liked, _, err := s.GetLikedQuotes(ctx, reader1, firstPage); err != nil || len(liked) != 0 {
+		t.Errorf("GetLikedQuotes error: expected no liked quotes, got %v, %v", liked, err)
+	}
+
+	// Test case 4: reconciliation repairs a counter that drifted
+	if _, err := testDb.Exec("UPDATE quotes SET like_count = 7 WHERE id = $1", q.Id); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.repo.(*SQLiteQuoteRepository).ReconcileLikeCounts(ctx); err != nil || n != 1 {
+		t.Fatalf("ReconcileLikeCounts error: expected 1 quote fixed, got %d, %v", n, err)
+	}
+	if got, err := s.repo.GetQuoteById(ctx, q.Id); err != nil || got.Likes != 1 {
+		t.Errorf("ReconcileLikeCounts error: expected 1 like, got %+v, %v", got, err)
+	}
+}
+
+func TestQuoteServiceAnonymousReads(t *testing.T) {
+	clearQuotes(t)
+	ctx := context.Background()
+	s := NewQuoteService(NewQuoteRepository(testDb), automod.Default())
+	author := testActor("author1", policy.RoleUser)
+	moderator := testActor("mod1", policy.RoleModerator)
+	anonymous := actor.Actor{}
+
+	published := createTestQuote(t, s, author, "Well begun is half done.", false)
+	createTestQuote(t, s, author, "Still waiting for review.", false)
+	if err := s.ApproveQuote(ctx, moderator, published.Id); err != nil {
+		t.Fatalf("ApproveQuote error: %v", err)
+	}
+
+	// Test case 1: anonymous listings only hold approved quotes
+	quotes, _, err := s.GetQuotes(ctx, anonymous, QuoteFilter{}, firstPage)
+	if err != nil || len(quotes) != 1 || quotes[0].Id != published.Id {
+		t.Errorf("GetQuotes error: expected the approved quote, got %v, %v", quotes, err)
+	}
+	quotes, _, err = s.GetQuotesByUserId(ctx, anonymous, author.UID, QuoteFilter{}, firstPage)
+	if err != nil || len(quotes) != 1 || quotes[0].Id != published.Id {
+		t.Errorf("GetQuotesByUserId error: expected the approved quote, got %v, %v", quotes, err)
+	}
+
+	// Test case 2: asking for anything else is refused
+	if _, _, err := s.GetQuotesByUserId(ctx, anonymous, author.UID, QuoteFilter{Status: StatusPending}, firstPage); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("GetQuotesByUserId error: expected ErrNotAuthorized, got %v", err)
+	}
+}
@@ -16,14 +16,14 @@ func MakeAdminHandler(ctx *gin.Context, service AdminService) {
 		return
 	}
 
-	email, err := validateInput(ctx, input)
+	email, err := validateInput(input)
 	if err != nil {
 		log.Printf("Error validating email: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := service.MakeAdmin(email); err != nil {
+	if err := service.MakeAdmin(ctx.Request.Context(), email); err != nil {
 		log.Printf("Error assigning admin role: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -40,14 +40,14 @@ func RemoveAdminHandler(ctx *gin.Context, service AdminService) {
 		return
 	}
 
-	email, err := validateInput(ctx, input)
+	email, err := validateInput(input)
 	if err != nil {
 		log.Printf("Error validating email: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := service.RemoveAdmin(email); err != nil {
+	if err := service.RemoveAdmin(ctx.Request.Context(), email); err != nil {
 		log.Printf("Error assigning user role: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -55,3 +55,61 @@ func RemoveAdminHandler(ctx *gin.Context, service AdminService) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("User %s admin rights have been revoked", input.Email)})
 }
+
+func MakeModeratorHandler(ctx *gin.Context, service AdminService) {
+	var input EmailInput
+	if err := ctx.BindJSON(&input); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	email, err := validateInput(input)
+	if err != nil {
+		log.Printf("Error validating email: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.MakeModerator(ctx.Request.Context(), email); err != nil {
+		log.Printf("Error assigning moderator role: %v", err)
+		switch err {
+		case ErrIsAdmin:
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("User %s is now a moderator", input.Email)})
+}
+
+func RemoveModeratorHandler(ctx *gin.Context, service AdminService) {
+	var input EmailInput
+	if err := ctx.BindJSON(&input); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	email, err := validateInput(input)
+	if err != nil {
+		log.Printf("Error validating email: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := service.RemoveModerator(ctx.Request.Context(), email); err != nil {
+		log.Printf("Error removing moderator role: %v", err)
+		switch err {
+		case ErrNotModerator:
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("User %s moderator rights have been revoked", input.Email)})
+}
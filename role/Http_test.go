@@ -0,0 +1,145 @@
+package role
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/cprime50/fire-go/policy"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClaimsStore keeps user records in memory, keyed by email.
+type fakeClaimsStore struct {
+	users map[string]*auth.UserRecord
+}
+
+func newFakeClaimsStore(roles map[string]string) *fakeClaimsStore {
+	f := &fakeClaimsStore{users: map[string]*auth.UserRecord{}}
+	for email, role := range roles {
+		user := &auth.UserRecord{UserInfo: &auth.UserInfo{UID: email, Email: email}}
+		if role != "" {
+			user.CustomClaims = map[string]interface{}{"role": role, role: true}
+		}
+		f.users[email] = user
+	}
+	return f
+}
+
+func (f *fakeClaimsStore) GetUserByEmail(ctx context.Context, email string) (*auth.UserRecord, error) {
+	return f.users[email], nil
+}
+
+func (f *fakeClaimsStore) SetCustomUserClaims(ctx context.Context, uid string, customClaims map[string]interface{}) error {
+	for _, user := range f.users {
+		if user.UID == uid {
+			user.CustomClaims = customClaims
+		}
+	}
+	return nil
+}
+
+func (f *fakeClaimsStore) role(email string) string {
+	role, _ := f.users[email].CustomClaims["role"].(string)
+	return role
+}
+
+func TestModeratorHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newFakeClaimsStore(map[string]string{
+		"user1@email.com":  policy.RoleUser,
+		"admin1@email.com": policy.RoleAdmin,
+		"new1@email.com":   "",
+	})
+	service := NewAdminService(store)
+
+	r := gin.New()
+	r.POST("/admin/moderator", func(c *gin.Context) { MakeModeratorHandler(c, service) })
+	r.DELETE("/admin/moderator", func(c *gin.Context) { RemoveModeratorHandler(c, service) })
+	send := func(method, email string) int {
+		body, _ := json.Marshal(EmailInput{Email: email})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/admin/moderator", bytes.NewReader(body)))
+		return w.Code
+	}
+
+	// Test case 1: granting makes a user a moderator
+	if code := send(http.MethodPost, "user1@email.com"); code != http.StatusOK {
+		t.Errorf("MakeModeratorHandler error: expected 200, got %d", code)
+	}
+	if role := store.role("user1@email.com"); role != policy.RoleModerator {
+		t.Errorf("MakeModeratorHandler error: expected moderator, got %q", role)
+	}
+
+	// Test case 2: revoking makes the moderator a user again
+	if code := send(http.MethodDelete, "user1@email.com"); code != http.StatusOK {
+		t.Errorf("RemoveModeratorHandler error: expected 200, got %d", code)
+	}
+	if role := store.role("user1@email.com"); role != policy.RoleUser {
+		t.Errorf("RemoveModeratorHandler error: expected user, got %q", role)
+	}
+
+	// Test case 3: an admin can be neither made a moderator nor revoked as one
+	if code := send(http.MethodPost, "admin1@email.com"); code != http.StatusConflict {
+		t.Errorf("MakeModeratorHandler error: expected 409 for an admin, got %d", code)
+	}
+	if code := send(http.MethodDelete, "admin1@email.com"); code != http.StatusConflict {
+		t.Errorf("RemoveModeratorHandler error: expected 409 for an admin, got %d", code)
+	}
+	if role := store.role("admin1@email.com"); role != policy.RoleAdmin {
+		t.Errorf("ModeratorHandlers error: expected the admin to keep their role, got %q", role)
+	}
+
+	// Test case 4: revoking someone who is not a moderator changes nothing
+	if code := send(http.MethodDelete, "user1@email.com"); code != http.StatusConflict {
+		t.Errorf("RemoveModeratorHandler error: expected 409 for a user, got %d", code)
+	}
+	if code := send(http.MethodDelete, "new1@email.com"); code != http.StatusConflict {
+		t.Errorf("RemoveModeratorHandler error: expected 409 for a user with no role, got %d", code)
+	}
+	if role := store.role("new1@email.com"); role != "" {
+		t.Errorf("RemoveModeratorHandler error: expected no role, got %q", role)
+	}
+
+	// Test case 5: an invalid email is refused
+	if code := send(http.MethodPost, "not-an-email"); code != http.StatusBadRequest {
+		t.Errorf("MakeModeratorHandler error: expected 400, got %d", code)
+	}
+}
+
+func TestAdminServiceModerators(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeClaimsStore(map[string]string{
+		"user1@email.com":  policy.RoleUser,
+		"admin1@email.com": policy.RoleAdmin,
+	})
+	s := NewAdminService(store)
+
+	// Test case 1: revoking an admin returns ErrNotModerator
+	if err := s.RemoveModerator(ctx, "admin1@email.com"); err != ErrNotModerator {
+		t.Errorf("RemoveModerator error: expected ErrNotModerator, got %v", err)
+	}
+
+	// Test case 2: granting to an admin returns ErrIsAdmin
+	if err := s.MakeModerator(ctx, "admin1@email.com"); err != ErrIsAdmin {
+		t.Errorf("MakeModerator error: expected ErrIsAdmin, got %v", err)
+	}
+
+	// Test case 3: the grant replaces the user role claim
+	if err := s.MakeModerator(ctx, "user1@email.com"); err != nil {
+		t.Fatalf("MakeModerator error: %v", err)
+	}
+	claims := store.users["user1@email.com"].CustomClaims
+	if claims[policy.RoleModerator] != true || claims[policy.RoleUser] != nil {
+		t.Errorf("MakeModerator error: unexpected claims %v", claims)
+	}
+
+	// Test case 4: an unknown email is an error
+	if err := s.RemoveModerator(ctx, "nobody@email.com"); err == nil {
+		t.Error("RemoveModerator error: expected an error for an unknown user")
+	}
+}
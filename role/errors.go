@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	ErrInvalidEmail = errors.New("invalid email")
+	ErrIsAdmin      = errors.New("user is an admin")
+	ErrNotModerator = errors.New("user is not a moderator")
 )
@@ -4,33 +4,72 @@ import (
 	"context"
 	"log"
 
-	"firebase.google.com/go/v4/auth"
 	"github.com/cprime50/fire-go/middleware"
+	"github.com/cprime50/fire-go/policy"
 )
 
 type AdminService interface {
-	MakeAdmin(email string) error
-	RemoveAdmin(email string) error
+	MakeAdmin(ctx context.Context, email string) error
+	RemoveAdmin(ctx context.Context, email string) error
+	MakeModerator(ctx context.Context, email string) error
+	RemoveModerator(ctx context.Context, email string) error
 }
 
 type AdminServiceImpl struct {
-	client *auth.Client
+	client middleware.ClaimsStore
 }
 
-func NewAdminService(client *auth.Client) *AdminServiceImpl {
+func NewAdminService(client middleware.ClaimsStore) *AdminServiceImpl {
 	return &AdminServiceImpl{client: client}
 }
 
-func (s *AdminServiceImpl) MakeAdmin(email string) error {
-	if err := middleware.AssignRole(context.Background(), s.client, email, "admin"); err != nil {
+func (s *AdminServiceImpl) MakeAdmin(ctx context.Context, email string) error {
+	if err := middleware.AssignRole(ctx, s.client, email, policy.RoleAdmin); err != nil {
 		log.Printf("Error assigning admin role: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (s *AdminServiceImpl) RemoveAdmin(email string) error {
-	if err := middleware.AssignRole(context.Background(), s.client, email, "user"); err != nil {
+func (s *AdminServiceImpl) RemoveAdmin(ctx context.Context, email string) error {
+	if err := middleware.AssignRole(ctx, s.client, email, policy.RoleUser); err != nil {
+		log.Printf("Error assigning user role: %v", err)
+		return err
+	}
+	return nil
+}
+
+// MakeModerator grants the moderator role. Admins are left alone so the
+// endpoint can't be used to demote one.
+func (s *AdminServiceImpl) MakeModerator(ctx context.Context, email string) error {
+	current, err := middleware.GetRole(ctx, s.client, email)
+	if err != nil {
+		log.Printf("Error getting role: %v", err)
+		return err
+	}
+	if current == policy.RoleAdmin {
+		log.Printf("MakeModerator: user %s is an admin", email)
+		return ErrIsAdmin
+	}
+	if err := middleware.AssignRole(ctx, s.client, email, policy.RoleModerator); err != nil {
+		log.Printf("Error assigning moderator role: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RemoveModerator demotes a moderator back to a regular user.
+func (s *AdminServiceImpl) RemoveModerator(ctx context.Context, email string) error {
+	current, err := middleware.GetRole(ctx, s.client, email)
+	if err != nil {
+		log.Printf("Error getting role: %v", err)
+		return err
+	}
+	if current != policy.RoleModerator {
+		log.Printf("RemoveModerator: user %s has role %q", email, current)
+		return ErrNotModerator
+	}
+	if err := middleware.AssignRole(ctx, s.client, email, policy.RoleUser); err != nil {
 		log.Printf("Error assigning user role: %v", err)
 		return err
 	}
@@ -1,19 +1,13 @@
 package role
 
 import (
-	"fmt"
 	"regexp"
-
-	"github.com/gin-gonic/gin"
 )
 
-func validateInput(ctx *gin.Context, input EmailInput) (string, error) {
-	if err := ctx.BindJSON(&input); err != nil {
-		return "", fmt.Errorf("invalid JSON format")
-	}
+func validateInput(input EmailInput) (string, error) {
 	emailOk := ValidateEmail(input.Email)
 	if !emailOk {
-		return "", fmt.Errorf("invalid email format")
+		return "", ErrInvalidEmail
 	}
 	return input.Email, nil
 }
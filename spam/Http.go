@@ -0,0 +1,67 @@
+package spam
+
+import (
+	"net/http"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func GetModelHandler(c *gin.Context, service SpamService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	stats, err := service.GetModel(c.Request.Context(), a)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"model": stats})
+}
+
+func RetrainHandler(c *gin.Context, service SpamService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	stats, err := service.Retrain(c.Request.Context(), a)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Spam model retrained successfully", "model": stats})
+}
+
+func ResetHandler(c *gin.Context, service SpamService) {
+	a, ok := getActorFromCtx(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		return
+	}
+
+	stats, err := service.Reset(c.Request.Context(), a)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Spam model reset successfully", "model": stats})
+}
+
+func writeError(c *gin.Context, err error) {
+	switch err {
+	case ErrNotAuthorized:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func getActorFromCtx(ctx *gin.Context) (actor.Actor, bool) {
+	return middleware.CurrentActor(ctx)
+}
@@ -0,0 +1,196 @@
+// Package spam learns what spam looks like from moderators' approve and
+// reject decisions. Its Classifier is a naive Bayes model kept in SQLite that
+// plugs into the automod chain, scoring every submission and flagging likely
+// spam.
+package spam
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cprime50/fire-go/automod"
+)
+
+const (
+	// ScoreName is the key of the spam score in automod results.
+	ScoreName = "spam"
+	// DefaultFlagAt is the spam probability from which submissions are
+	// flagged.
+	DefaultFlagAt = 0.9
+	// DefaultMinExamples is how many approved and how many rejected texts the
+	// model needs before it scores anything.
+	DefaultMinExamples = 20
+
+	maxTokenLength = 40
+)
+
+// Classifier scores submissions against the learned model. It is an
+// automod.Learner, so the quote service trains it on every human review.
+type Classifier struct {
+	repo SpamRepository
+	// FlagAt and MinExamples default to DefaultFlagAt and DefaultMinExamples.
+	FlagAt      float64
+	MinExamples int
+
+	mu    sync.RWMutex
+	model *Model
+}
+
+// NewClassifier loads the stored model.
+func NewClassifier(ctx context.Context, repo SpamRepository) (*Classifier, error) {
+	m, err := repo.GetModel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("spam.NewClassifier: %w", err)
+	}
+	return &Classifier{repo: repo, FlagAt: DefaultFlagAt, MinExamples: DefaultMinExamples, model: m}, nil
+}
+
+// Moderate scores s and flags it when it is probably spam. Until the model is
+// ready it has no opinion.
+func (c *Classifier) Moderate(ctx context.Context, s automod.Submission) automod.Result {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.ready() {
+		return automod.Allowed
+	}
+	score := c.model.score(tokenize(s.Text))
+	result := automod.Result{Decision: automod.Allow, Risk: score, Scores: map[string]float64{ScoreName: score}}
+	if score >= c.FlagAt {
+		result.Decision = automod.Flag
+		result.Reasons = []string{fmt.Sprintf("looks like spam (%.0f%%)", 100*score)}
+	}
+	return result
+}
+
+// Learn adds a reviewed text to the model.
+func (c *Classifier) Learn(ctx context.Context, s automod.Submission, rejected bool) error {
+	tokens := tokenize(s.Text)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if err := c.repo.AddExample(ctx, tokens, rejected, now); err != nil {
+		return fmt.Errorf("spam.Learn: %w", err)
+	}
+	c.model.add(tokens, rejected)
+	c.model.TrainedAt = &now
+	return nil
+}
+
+// Retrain rebuilds the model from every decision moderators have made.
+func (c *Classifier) Retrain(ctx context.Context) (*ModelStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	examples, err := c.repo.GetExamples(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("spam.Retrain: %w", err)
+	}
+	now := time.Now()
+	m := &Model{Tokens: map[string]*TokenCount{}, TrainedAt: &now}
+	for _, e := range examples {
+		m.add(tokenize(e.Text), e.Spam)
+	}
+	if err := c.repo.SaveModel(ctx, m); err != nil {
+		return nil, fmt.Errorf("spam.Retrain: %w", err)
+	}
+	c.model = m
+	return c.stats(), nil
+}
+
+// Reset forgets everything the model has learned.
+func (c *Classifier) Reset(ctx context.Context) (*ModelStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := &Model{Tokens: map[string]*TokenCount{}}
+	if err := c.repo.SaveModel(ctx, m); err != nil {
+		return nil, fmt.Errorf("spam.Reset: %w", err)
+	}
+	c.model = m
+	return c.stats(), nil
+}
+
+// Stats describes the current model.
+func (c *Classifier) Stats() *ModelStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats()
+}
+
+func (c *Classifier) stats() *ModelStats {
+	return &ModelStats{
+		HamDocs:    c.model.HamDocs,
+		SpamDocs:   c.model.SpamDocs,
+		Vocabulary: len(c.model.Tokens),
+		Ready:      c.ready(),
+		TrainedAt:  c.model.TrainedAt,
+	}
+}
+
+// ready reports whether the model has seen enough of both kinds of text.
+func (c *Classifier) ready() bool {
+	need := max(c.MinExamples, 1)
+	return c.model.HamDocs >= need && c.model.SpamDocs >= need
+}
+
+func (m *Model) add(tokens []string, spam bool) {
+	if spam {
+		m.SpamDocs++
+		m.SpamTokens += len(tokens)
+	} else {
+		m.HamDocs++
+		m.HamTokens += len(tokens)
+	}
+	for _, token := range tokens {
+		count := m.Tokens[token]
+		if count == nil {
+			count = &TokenCount{}
+			m.Tokens[token] = count
+		}
+		if spam {
+			count.Spam++
+		} else {
+			count.Ham++
+		}
+	}
+}
+
+// score returns the probability that a text with these tokens is spam.
+// Token probabilities use add-one smoothing; tokens the model has never seen
+// say nothing either way and are skipped.
+func (m *Model) score(tokens []string) float64 {
+	vocabulary := float64(len(m.Tokens))
+	docs := float64(m.HamDocs + m.SpamDocs)
+	logHam := math.Log(float64(m.HamDocs) / docs)
+	logSpam := math.Log(float64(m.SpamDocs) / docs)
+	for _, token := range tokens {
+		count, ok := m.Tokens[token]
+		if !ok {
+			continue
+		}
+		logHam += math.Log((float64(count.Ham) + 1) / (float64(m.HamTokens) + vocabulary))
+		logSpam += math.Log((float64(count.Spam) + 1) / (float64(m.SpamTokens) + vocabulary))
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam))
+}
+
+// tokenize returns the distinct lowercase words of text. Single characters
+// and very long runs carry little signal and are dropped.
+func tokenize(text string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if n := utf8.RuneCountInString(word); n < 2 || n > maxTokenLength || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
@@ -0,0 +1,140 @@
+package spam
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cprime50/fire-go/automod"
+	"github.com/cprime50/fire-go/db"
+)
+
+var testDb *sql.DB
+
+func TestMain(m *testing.M) {
+	var err error
+	testDb, err = db.ConnectTest()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = db.Migrate(testDb); err != nil {
+		log.Fatal(err)
+	}
+	defer testDb.Close()
+
+	os.Exit(m.Run())
+}
+
+var (
+	hamTexts = []string{
+		"The only way to do great work is to love what you do.",
+		"Simplicity is prerequisite for reliability.",
+		"Well begun is half done.",
+	}
+	spamTexts = []string{
+		"Win free money now, claim your prize today",
+		"Cheap pills, free shipping, claim now",
+		"Free prize for you, claim it now",
+	}
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Free money! FREE money, a prize: 100%")
+	if want := []string{"free", "money", "prize", "100"}; !slices.Equal(got, want) {
+		t.Errorf("tokenize error: expected %v, got %v", want, got)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	ctx := context.Background()
+	repo := NewSpamRepository(testDb)
+	c, err := NewClassifier(ctx, repo)
+	if err != nil {
+		t.Fatalf("NewClassifier error: %v", err)
+	}
+	if _, err := c.Reset(ctx); err != nil {
+		t.Fatalf("Reset error: %v", err)
+	}
+	c.MinExamples = len(hamTexts)
+	spammy := automod.Submission{Text: "Claim your free prize now"}
+	clean := automod.Submission{Text: "Great work takes love."}
+
+	// Test case 1: an untrained model has no opinion
+	if got := c.Moderate(ctx, spammy); got.Decision != automod.Allow || got.Scores != nil {
+		t.Errorf("Moderate error: expected no opinion, got %+v", got)
+	}
+
+	// Test case 2: once trained, spam is flagged and clean text is not
+	for _, text := range hamTexts {
+		if err := c.Learn(ctx, automod.Submission{Text: text}, false); err != nil {
+			t.Fatalf("Learn error: %v", err)
+		}
+	}
+	for _, text := range spamTexts {
+		if err := c.Learn(ctx, automod.Submission{Text: text}, true); err != nil {
+			t.Fatalf("Learn error: %v", err)
+		}
+	}
+	flagged := c.Moderate(ctx, spammy)
+	if flagged.Decision != automod.Flag || flagged.Scores[ScoreName] < c.FlagAt {
+		t.Errorf("Moderate error: expected spam to be flagged, got %+v", flagged)
+	}
+	if got := c.Moderate(ctx, clean); got.Decision != automod.Allow || got.Scores[ScoreName] > 0.5 {
+		t.Errorf("Moderate error: expected clean text to pass, got %+v", got)
+	}
+
+	// Test case 3: the model survives a restart
+	reloaded, err := NewClassifier(ctx, repo)
+	if err != nil {
+		t.Fatalf("NewClassifier error: %v", err)
+	}
+	reloaded.MinExamples = c.MinExamples
+	if got := reloaded.Moderate(ctx, spammy); got.Scores[ScoreName] != flagged.Scores[ScoreName] {
+		t.Errorf("NewClassifier error: expected score %v after reload, got %+v", flagged.Scores[ScoreName], got)
+	}
+
+	// Test case 4: retraining rebuilds the model from moderators' decisions only
+	if _, err := testDb.Exec("DELETE FROM quote_revisions; DELETE FROM quotes"); err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range append(hamTexts, spamTexts...) {
+		status, reviewer := "approved", "mod1"
+		if i >= len(hamTexts) {
+			status = "rejected"
+		}
+		if i == 0 {
+			reviewer = automod.ReviewerId
+		}
+		if _, err := testDb.Exec("INSERT INTO quotes (id, user_id, quote, status) VALUES ($1, 'author1', $2, $3)", i, text, status); err != nil {
+			t.Fatal(err)
+		}
+		_, err := testDb.Exec("INSERT INTO quote_revisions (id, quote_id, revision, quote, edited_by, status, reviewed_by, created_at) VALUES ($1, $2, 1, $3, 'author1', $4, $5, $6)",
+			i, i, text, status, reviewer, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := c.Retrain(ctx)
+	if err != nil {
+		t.Fatalf("Retrain error: %v", err)
+	}
+	if stats.HamDocs != len(hamTexts)-1 || stats.SpamDocs != len(spamTexts) || stats.Ready || stats.TrainedAt == nil {
+		t.Errorf("Retrain error: unexpected stats %+v", stats)
+	}
+
+	// Test case 5: resetting forgets everything
+	stats, err = c.Reset(ctx)
+	if err != nil {
+		t.Fatalf("Reset error: %v", err)
+	}
+	if stats.HamDocs != 0 || stats.SpamDocs != 0 || stats.Vocabulary != 0 {
+		t.Errorf("Reset error: unexpected stats %+v", stats)
+	}
+	if m, err := repo.GetModel(ctx); err != nil || len(m.Tokens) != 0 || m.HamDocs != 0 {
+		t.Errorf("Reset error: model still stored: %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,10 @@
+package spam
+
+import "errors"
+
+var (
+	ErrNotAuthorized  = errors.New("unauthorized access")
+	ErrGettingModel   = errors.New("failed to get spam model")
+	ErrTrainingModel  = errors.New("failed to train spam model")
+	ErrResettingModel = errors.New("failed to reset spam model")
+)
@@ -0,0 +1,36 @@
+package spam
+
+import "time"
+
+// Model is a naive Bayes model of the texts moderators approved (ham) and
+// rejected (spam). Each text counts a token once, however often it repeats.
+type Model struct {
+	HamDocs    int
+	SpamDocs   int
+	HamTokens  int
+	SpamTokens int
+	Tokens     map[string]*TokenCount
+	TrainedAt  *time.Time
+}
+
+// TokenCount is how many ham and spam texts a token appeared in.
+type TokenCount struct {
+	Ham  int
+	Spam int
+}
+
+// Example is a reviewed text to train on.
+type Example struct {
+	Text string
+	Spam bool
+}
+
+// ModelStats is what admins see of the model. Ready is false until the
+// model has seen enough of both kinds of text to score submissions.
+type ModelStats struct {
+	HamDocs    int        `json:"ham_docs"`
+	SpamDocs   int        `json:"spam_docs"`
+	Vocabulary int        `json:"vocabulary"`
+	Ready      bool       `json:"ready"`
+	TrainedAt  *time.Time `json:"trained_at,omitempty"`
+}
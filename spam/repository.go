@@ -0,0 +1,156 @@
+package spam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cprime50/fire-go/automod"
+)
+
+type SpamRepository interface {
+	GetModel(ctx context.Context) (*Model, error)
+	AddExample(ctx context.Context, tokens []string, spam bool, at time.Time) error
+	SaveModel(ctx context.Context, m *Model) error
+	GetExamples(ctx context.Context) ([]Example, error)
+}
+
+// SQLiteSpamRepository stores the model in the spam_model and spam_tokens
+// tables, and reads training examples from quote_revisions.
+type SQLiteSpamRepository struct {
+	db *sql.DB
+}
+
+func NewSpamRepository(db *sql.DB) *SQLiteSpamRepository {
+	return &SQLiteSpamRepository{db: db}
+}
+
+// GetModel loads the whole model into memory.
+func (r *SQLiteSpamRepository) GetModel(ctx context.Context) (*Model, error) {
+	m := &Model{Tokens: map[string]*TokenCount{}}
+	var trainedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, "SELECT ham_docs, spam_docs, ham_tokens, spam_tokens, trained_at FROM spam_model WHERE id = 1").
+		Scan(&m.HamDocs, &m.SpamDocs, &m.HamTokens, &m.SpamTokens, &trainedAt)
+	if err != nil {
+		return nil, fmt.Errorf("GetModel: %w", err)
+	}
+	if trainedAt.Valid {
+		m.TrainedAt = &trainedAt.Time
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT token, ham, spam FROM spam_tokens")
+	if err != nil {
+		return nil, fmt.Errorf("GetModel tokens: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var token string
+		count := &TokenCount{}
+		if err := rows.Scan(&token, &count.Ham, &count.Spam); err != nil {
+			return nil, fmt.Errorf("GetModel scan: %w", err)
+		}
+		m.Tokens[token] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetModel rows: %w", err)
+	}
+	return m, nil
+}
+
+// AddExample counts one more ham or spam text with the given unique tokens.
+func (r *SQLiteSpamRepository) AddExample(ctx context.Context, tokens []string, spam bool, at time.Time) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		ham, spammy := 1, 0
+		if spam {
+			ham, spammy = 0, 1
+		}
+		_, err := tx.ExecContext(ctx,
+			`UPDATE spam_model SET ham_docs = ham_docs + $1, spam_docs = spam_docs + $2,
+				ham_tokens = ham_tokens + $3, spam_tokens = spam_tokens + $4, trained_at = $5
+			WHERE id = 1`,
+			ham, spammy, ham*len(tokens), spammy*len(tokens), at,
+		)
+		if err != nil {
+			return fmt.Errorf("AddExample model: %w", err)
+		}
+		stmt, err := tx.PrepareContext(ctx,
+			`INSERT INTO spam_tokens (token, ham, spam) VALUES ($1, $2, $3)
+			ON CONFLICT (token) DO UPDATE SET ham = ham + excluded.ham, spam = spam + excluded.spam`)
+		if err != nil {
+			return fmt.Errorf("AddExample prepare: %w", err)
+		}
+		defer stmt.Close()
+		for _, token := range tokens {
+			if _, err := stmt.ExecContext(ctx, token, ham, spammy); err != nil {
+				return fmt.Errorf("AddExample token: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// SaveModel replaces the stored model with m.
+func (r *SQLiteSpamRepository) SaveModel(ctx context.Context, m *Model) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE spam_model SET ham_docs = $1, spam_docs = $2, ham_tokens = $3, spam_tokens = $4, trained_at = $5 WHERE id = 1",
+			m.HamDocs, m.SpamDocs, m.HamTokens, m.SpamTokens, m.TrainedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("SaveModel model: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM spam_tokens"); err != nil {
+			return fmt.Errorf("SaveModel delete: %w", err)
+		}
+		stmt, err := tx.PrepareContext(ctx, "INSERT INTO spam_tokens (token, ham, spam) VALUES ($1, $2, $3)")
+		if err != nil {
+			return fmt.Errorf("SaveModel prepare: %w", err)
+		}
+		defer stmt.Close()
+		for token, count := range m.Tokens {
+			if _, err := stmt.ExecContext(ctx, token, count.Ham, count.Spam); err != nil {
+				return fmt.Errorf("SaveModel token: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetExamples returns every quote text a moderator approved or rejected,
+// including edits to approved quotes. Automatic approvals are left out.
+func (r *SQLiteSpamRepository) GetExamples(ctx context.Context) ([]Example, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT quote, status FROM quote_revisions
+		WHERE status IN ('approved', 'rejected') AND reviewed_by IS NOT NULL AND reviewed_by <> $1`,
+		automod.ReviewerId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetExamples: %w", err)
+	}
+	defer rows.Close()
+	var examples []Example
+	for rows.Next() {
+		var text, status string
+		if err := rows.Scan(&text, &status); err != nil {
+			return nil, fmt.Errorf("GetExamples scan: %w", err)
+		}
+		examples = append(examples, Example{Text: text, Spam: status == "rejected"})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetExamples rows: %w", err)
+	}
+	return examples, nil
+}
+
+func (r *SQLiteSpamRepository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
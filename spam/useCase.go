@@ -0,0 +1,61 @@
+package spam
+
+import (
+	"context"
+	"log"
+
+	"github.com/cprime50/fire-go/actor"
+	"github.com/cprime50/fire-go/policy"
+)
+
+type SpamService interface {
+	GetModel(ctx context.Context, a actor.Actor) (*ModelStats, error)
+	Retrain(ctx context.Context, a actor.Actor) (*ModelStats, error)
+	Reset(ctx context.Context, a actor.Actor) (*ModelStats, error)
+}
+
+type SpamServiceImpl struct {
+	classifier *Classifier
+}
+
+func NewSpamService(classifier *Classifier) *SpamServiceImpl {
+	return &SpamServiceImpl{classifier: classifier}
+}
+
+func (s *SpamServiceImpl) GetModel(ctx context.Context, a actor.Actor) (*ModelStats, error) {
+	if !policy.Can(a, policy.SpamManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, ErrNotAuthorized
+	}
+	return s.classifier.Stats(), nil
+}
+
+// Retrain rebuilds the model from scratch out of every moderator decision on
+// record, for instance after Reset or after changing how texts are tokenized.
+func (s *SpamServiceImpl) Retrain(ctx context.Context, a actor.Actor) (*ModelStats, error) {
+	if !policy.Can(a, policy.SpamManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, ErrNotAuthorized
+	}
+	stats, err := s.classifier.Retrain(ctx)
+	if err != nil {
+		log.Println("Error retraining spam model:", err)
+		return nil, ErrTrainingModel
+	}
+	return stats, nil
+}
+
+// Reset empties the model. It stops scoring until it has learned from enough
+// new decisions or is retrained.
+func (s *SpamServiceImpl) Reset(ctx context.Context, a actor.Actor) (*ModelStats, error) {
+	if !policy.Can(a, policy.SpamManage, policy.Any) {
+		log.Println("Error: Not authorized")
+		return nil, ErrNotAuthorized
+	}
+	stats, err := s.classifier.Reset(ctx)
+	if err != nil {
+		log.Println("Error resetting spam model:", err)
+		return nil, ErrResettingModel
+	}
+	return stats, nil
+}